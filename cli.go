@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+const usage = `Usage: traefik-config-middleware [command] [flags]
+
+Commands:
+  serve      Poll downstreams and serve the aggregated config (default)
+  validate   Check a config file and report all problems
+  render     Aggregate once and print the config upstream Traefik would receive
+
+Run 'traefik-config-middleware <command> -h' for the flags of a command.
+`
+
+// run dispatches to the subcommand named by the first argument and returns the exit code.
+// Without a command, or when the first argument is a flag, the server is started.
+func run(args []string) int {
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "validate":
+		return runValidate(args, os.Stdout, os.Stderr)
+	case "render":
+		return runRender(args, os.Stdout, os.Stderr)
+	case "help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+}
+
+// newFlagSet creates the flag set of a subcommand with the shared -c flag. The config
+// path defaults to $CONFIG_PATH, then config.yml.
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	defaultPath := os.Getenv("CONFIG_PATH")
+	if defaultPath == "" {
+		defaultPath = defaultConfigFile
+	}
+	configPath := fs.String("c", defaultPath, "path to the config file")
+	return fs, configPath
+}
+
+// parseFlags parses args and maps -h and parse errors to exit codes
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, false
+		}
+		return 2, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2, false
+	}
+	return 0, true
+}
+
+func runServe(args []string) int {
+	fs, configPath := newFlagSet("serve", os.Stderr)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	return serve(*configPath)
+}
+
+// runValidate loads the config file and reports whether it is valid. Exits 1 if it is not.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs, configPath := newFlagSet("validate", stderr)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if _, err := aggregator.LoadConfig(*configPath); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *configPath, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: OK\n", *configPath)
+	return 0
+}
+
+// runRender performs a single aggregation and prints the result. Downstreams that
+// fail are reported on stderr and make the command exit 1, since their routes would
+// be missing from the output.
+func runRender(args []string, stdout, stderr io.Writer) int {
+	fs, configPath := newFlagSet("render", stderr)
+	formatName := fs.String("format", string(aggregator.FormatJSON), "output format: json, yaml or toml")
+	recordings := fs.String("recordings", "", "serve downstream API responses from recorded JSON files in this directory instead of the network")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	format, err := aggregator.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	cfg, err := aggregator.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *configPath, err)
+		return 1
+	}
+	// The output goes to stdout, so it is never also written to the output file
+	cfg.OutputFile = nil
+
+	logger, err := aggregator.NewLogger(stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	client := &http.Client{Timeout: httpTimeout(cfg)}
+	if *recordings != "" {
+		client.Transport = aggregator.NewRecordedTransport(*recordings, cfg.Downstream)
+	}
+
+	renderAgg := aggregator.NewAggregator(cfg, client)
+	renderAgg.SetLogger(logger)
+	renderAgg.AggregateConfigs(context.Background())
+
+	if err := aggregator.EncodeConfig(stdout, renderAgg.GetCachedConfig(), format); err != nil {
+		fmt.Fprintf(stderr, "encoding config: %v\n", err)
+		return 1
+	}
+
+	errs := renderAgg.DownstreamErrors()
+	if len(errs) == 0 {
+		return 0
+	}
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(stderr, "downstream %s: %v\n", name, errs[name])
+	}
+	return 1
+}
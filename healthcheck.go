@@ -3,14 +3,30 @@
 package main
 
 import (
+	"crypto/tls"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
+// defaultHealthcheckURL is probed unless HEALTHCHECK_URL is set, e.g. to match a
+// custom listen address or https://localhost:8443/ready with server_tls
+const defaultHealthcheckURL = "http://localhost:8080/ready"
+
 func main() {
+	url := os.Getenv("HEALTHCHECK_URL")
+	if url == "" {
+		url = defaultHealthcheckURL
+	}
+
 	client := &http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get("http://localhost:8080/health")
+	if strings.HasPrefix(url, "https://") {
+		// The probe targets the local process, whose certificate is issued for its public name
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	resp, err := client.Get(url)
 	if err != nil || resp.StatusCode != http.StatusOK {
 		os.Exit(1)
 	}
@@ -1,37 +1,90 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"traefik-config-middleware/pkg/aggregator"
 )
 
 const (
-	defaultPollInterval = 30 * time.Second
 	defaultHTTPTimeout  = 10 * time.Second
 	defaultConfigFile   = "config.yml"
-	defaultListenAddr   = ":8080"
+	configWatchInterval = 5 * time.Second
+	// shutdownTimeout bounds how long in-flight requests may take to drain on shutdown
+	shutdownTimeout = 10 * time.Second
 )
 
 var (
-	config     *aggregator.Config
-	agg        *aggregator.Aggregator
-	httpClient = &http.Client{
-		Timeout: defaultHTTPTimeout,
-	}
+	config *aggregator.Config
+	agg    *aggregator.Aggregator
 )
 
 func getTraefikConfig(w http.ResponseWriter, r *http.Request) {
-	cachedConfig := agg.GetCachedConfig()
+	code := http.StatusOK
+	// ?format= takes precedence over the Accept header
+	format := aggregator.FormatFromAccept(r.Header.Get("Accept"))
+	defer func() { agg.Metrics().ObserveConfigRequest(format, code) }()
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(cachedConfig); err != nil {
-		log.Printf("Error encoding config response: %v", err)
+	if name := r.URL.Query().Get("format"); name != "" {
+		parsed, err := aggregator.ParseFormat(name)
+		if err != nil {
+			format = "invalid"
+			code = http.StatusBadRequest
+			http.Error(w, err.Error(), code)
+			return
+		}
+		format = parsed
+	}
+
+	data, version, err := agg.GetEncodedConfig(format)
+	if err != nil {
+		slog.Error("Error encoding config response", "format", format, "error", err)
+		code = http.StatusInternalServerError
+		http.Error(w, "failed to encode config", code)
+		return
+	}
+
+	if stale := agg.StaleDownstreams(); len(stale) > 0 {
+		w.Header().Set("X-Stale-Downstreams", strings.Join(stale, ","))
+	}
+	etag := version.ETag(format)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", version.Modified.Format(http.TimeFormat))
+	w.Header().Set("Vary", "Accept")
+
+	if notModified(r, etag, version.Modified) {
+		code = http.StatusNotModified
+		w.WriteHeader(code)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	if _, err := w.Write(data); err != nil {
+		slog.Warn("Error writing config response", "error", err)
+	}
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as specified by RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return aggregator.MatchesETag(ifNoneMatch, etag)
+	}
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !modified.After(since)
 	}
+	return false
 }
 
 func healthCheck(w http.ResponseWriter, r *http.Request) {
@@ -39,52 +92,193 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func pollLoop() {
-	duration, err := time.ParseDuration(config.PollInterval)
+// getStatus reports the fetch state of every downstream as JSON
+func getStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(agg.Status()); err != nil {
+		slog.Warn("Error writing status response", "error", err)
+	}
+}
+
+// readyCheck succeeds once the aggregated config is complete enough to be served
+func readyCheck(w http.ResponseWriter, r *http.Request) {
+	probe(w, agg.Ready())
+}
+
+// liveCheck fails when the poll loop has stopped completing aggregations
+func liveCheck(w http.ResponseWriter, r *http.Request) {
+	probe(w, agg.Live())
+}
+
+func probe(w http.ResponseWriter, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func httpTimeout(cfg *aggregator.Config) time.Duration {
+	if cfg.HTTPTimeout != "" {
+		if parsed, err := time.ParseDuration(cfg.HTTPTimeout); err == nil {
+			return parsed
+		}
+	}
+	return defaultHTTPTimeout
+}
+
+// reloadConfig loads and validates the config file and swaps it into the aggregator,
+// which aggregates it right away. An invalid file is logged and the current
+// configuration is kept.
+func reloadConfig(configPath string) {
+	newConfig, err := aggregator.LoadConfig(configPath)
 	if err != nil {
-		duration = defaultPollInterval
+		slog.Error("Config reload failed, keeping current config", "path", configPath, "error", err)
+		return
 	}
 
-	ticker := time.NewTicker(duration)
-	defer ticker.Stop()
+	logger, err := aggregator.NewLogger(os.Stderr, newConfig.LogLevel, newConfig.LogFormat)
+	if err != nil {
+		slog.Error("Config reload failed, keeping current config", "path", configPath, "error", err)
+		return
+	}
+	slog.SetDefault(logger)
+	agg.SetLogger(logger)
 
-	// Initial fetch
-	agg.AggregateConfigs()
+	if newConfig.ListenAddr() != config.ListenAddr() ||
+		!reflect.DeepEqual(newConfig.ServerTLS, config.ServerTLS) ||
+		!reflect.DeepEqual(newConfig.Auth, config.Auth) {
+		slog.Warn("Changes to listen, server_tls and auth take effect after a restart", "path", configPath)
+	}
 
-	for range ticker.C {
-		agg.AggregateConfigs()
+	if httpTimeout(newConfig) != httpTimeout(config) {
+		agg.SetHTTPClient(&http.Client{Timeout: httpTimeout(newConfig)})
 	}
+	config = newConfig
+	agg.SetConfig(newConfig)
+
+	slog.Info("Config reloaded", "path", configPath, "downstreams", len(newConfig.Downstream))
 }
 
-func main() {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = defaultConfigFile
+// watchReloads reloads the config when the file changes or SIGHUP is received,
+// until ctx is cancelled
+func watchReloads(ctx context.Context, configPath string) {
+	changes := make(chan struct{}, 1)
+	go aggregator.WatchConfigFile(ctx, configPath, configWatchInterval, changes)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			slog.Info("Received SIGHUP, reloading config", "path", configPath)
+			reloadConfig(configPath)
+		case <-changes:
+			reloadConfig(configPath)
+		}
 	}
+}
 
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// serve loads the config and serves the aggregated configuration until SIGINT or
+// SIGTERM, then stops polling, cancels in-flight fetches and drains the HTTP server
+func serve(configPath string) int {
 	var err error
 	config, err = aggregator.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		slog.Error("Failed to load config", "path", configPath, "error", err)
+		return 1
 	}
 
-	// Configure HTTP client timeout from config
-	timeout := defaultHTTPTimeout
-	if config.HTTPTimeout != "" {
-		if parsed, err := time.ParseDuration(config.HTTPTimeout); err == nil {
-			timeout = parsed
-		}
+	logger, err := aggregator.NewLogger(os.Stderr, config.LogLevel, config.LogFormat)
+	if err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		return 1
 	}
-	httpClient.Timeout = timeout
+	slog.SetDefault(logger)
 
 	// Create aggregator with config and HTTP client
-	agg = aggregator.NewAggregator(config, httpClient)
+	agg = aggregator.NewAggregator(config, &http.Client{Timeout: httpTimeout(config)})
+
+	// Listener settings are fixed at startup and copied, since watchReloads swaps the
+	// global config while the server runs
+	listenAddr, serverTLS, auth := config.ListenAddr(), config.ServerTLS, config.Auth
+
+	tlsConfig, err := aggregator.BuildServerTLSConfig(serverTLS)
+	if err != nil {
+		slog.Error("Invalid server TLS configuration", "error", err)
+		return 1
+	}
 
-	http.HandleFunc("/traefik-config", getTraefikConfig)
+	// Endpoints exposing routes or downstream names need a client certificate (with
+	// client_ca_file) and credentials (with auth); health probes are always open
+	protect := func(handler http.Handler) http.Handler {
+		return aggregator.RequireClientCert(serverTLS, aggregator.RequireAuth(auth, handler))
+	}
+	http.Handle("/traefik-config", protect(http.HandlerFunc(getTraefikConfig)))
+	http.Handle("/status", protect(http.HandlerFunc(getStatus)))
+	http.Handle("/metrics", protect(agg.Metrics()))
 	http.HandleFunc("/health", healthCheck)
+	http.HandleFunc("/ready", readyCheck)
+	http.HandleFunc("/live", liveCheck)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		agg.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		watchReloads(ctx, configPath)
+	}()
 
-	go pollLoop()
+	server := &http.Server{
+		Addr:      listenAddr,
+		TLSConfig: tlsConfig,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("SNI Config Aggregator starting", "addr", server.Addr, "tls", tlsConfig != nil, "auth", auth != nil)
+		if tlsConfig != nil {
+			// Certificates are already loaded into TLSConfig
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("HTTP server stopped", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown failed", "error", err)
+		exitCode = 1
+	}
 
-	log.Printf("SNI Config Aggregator starting on %s", defaultListenAddr)
-	log.Fatal(http.ListenAndServe(defaultListenAddr, nil))
+	wg.Wait()
+	return exitCode
 }
@@ -1,29 +1,178 @@
 package aggregator
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Aggregator manages the configuration aggregation from downstream Traefik instances
 type Aggregator struct {
 	config       *Config
 	cachedConfig HTTPProxyConfig
+	version      ConfigVersion
+	encoded      map[Format][]byte
 	configMutex  sync.RWMutex
-	httpClient   *http.Client
+	httpClient   atomic.Pointer[http.Client]
+	fileSink     *FileSink
+	metrics      *Metrics
+	logger       atomic.Pointer[slog.Logger]
+
+	// Signalled by SetConfig so Run aggregates the new configuration right away
+	configChanged chan struct{}
+
+	// Last successfully built configuration per downstream, keyed by name
+	lastGood   map[string]downstreamSnapshot
+	stale      map[string]bool
+	downstream map[string]*downstreamState
+	stateMutex sync.Mutex
+
+	// Outcome of the latest completed aggregation, for readiness and liveness
+	createdAt          time.Time
+	lastAggregation    time.Time
+	healthyDownstreams int
+	totalDownstreams   int
 }
 
-// NewAggregator creates a new Aggregator with the given configuration and HTTP client
+// NewAggregator creates a new Aggregator with the given configuration and HTTP client.
+// If the configuration has an output_file, aggregated results are also written to disk.
 func NewAggregator(config *Config, client *http.Client) *Aggregator {
-	return &Aggregator{
-		config:     config,
-		httpClient: client,
+	a := &Aggregator{
+		config:        config,
+		lastGood:      make(map[string]downstreamSnapshot),
+		stale:         make(map[string]bool),
+		downstream:    make(map[string]*downstreamState),
+		metrics:       NewMetrics(),
+		configChanged: make(chan struct{}, 1),
+		createdAt:     time.Now(),
+	}
+	a.httpClient.Store(client)
+	a.logger.Store(slog.Default())
+
+	a.setCachedConfig(HTTPProxyConfig{})
+	a.fileSink = a.newFileSink(config)
+
+	return a
+}
+
+// newFileSink creates the file sink for the configuration's output_file, if any
+func (a *Aggregator) newFileSink(config *Config) *FileSink {
+	if config.OutputFile == nil {
+		return nil
+	}
+	sink, err := NewFileSink(config.OutputFile.Path, config.OutputFile.Format)
+	if err != nil {
+		a.log().Error("Output file disabled", "error", err)
+		return nil
+	}
+	return sink
+}
+
+// SetConfig atomically replaces the configuration used by subsequent aggregations.
+// The cached configuration keeps being served until the next AggregateConfigs call.
+// Cached state of downstreams no longer present in the new configuration is dropped.
+func (a *Aggregator) SetConfig(config *Config) {
+	sink := a.newFileSink(config)
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	a.config = config
+	a.fileSink = sink
+
+	present := make(map[string]bool, len(config.Downstream))
+	for _, ds := range config.Downstream {
+		present[ds.Name] = true
+	}
+	for name := range a.lastGood {
+		if !present[name] {
+			delete(a.lastGood, name)
+			delete(a.stale, name)
+		}
+	}
+	for name := range a.downstream {
+		if !present[name] {
+			delete(a.downstream, name)
+		}
+	}
+	a.metrics.retainDownstreams(present)
+
+	select {
+	case a.configChanged <- struct{}{}:
+	default:
 	}
 }
 
+// SetHTTPClient replaces the HTTP client used for subsequent downstream fetches
+func (a *Aggregator) SetHTTPClient(client *http.Client) {
+	a.httpClient.Store(client)
+}
+
+func (a *Aggregator) client() *http.Client {
+	return a.httpClient.Load()
+}
+
+// Run aggregates immediately and then polls every downstream on its own poll_interval
+// until ctx is cancelled, which also cancels in-flight downstream fetches. Whenever a
+// downstream is due it is refetched and the combined configuration re-merged, with the
+// other downstreams contributing their previous results. A configuration swapped in
+// with SetConfig is aggregated right away.
+func (a *Aggregator) Run(ctx context.Context) {
+	a.AggregateConfigs(ctx)
+
+	timer := time.NewTimer(a.untilNextPoll())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			a.aggregate(ctx, true)
+		case <-a.configChanged:
+			a.AggregateConfigs(ctx)
+		}
+		timer.Reset(a.untilNextPoll())
+	}
+}
+
+// untilNextPoll returns the time until the next downstream is due
+func (a *Aggregator) untilNextPoll() time.Duration {
+	config, _ := a.currentSettings()
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	if len(config.Downstream) == 0 {
+		return config.PollIntervalDuration()
+	}
+
+	var next time.Time
+	for _, ds := range config.Downstream {
+		state, ok := a.downstream[ds.Name]
+		if !ok || state.nextPoll.IsZero() {
+			return 0
+		}
+		if next.IsZero() || state.nextPoll.Before(next) {
+			next = state.nextPoll
+		}
+	}
+	return max(time.Until(next), 0)
+}
+
+// currentSettings returns the configuration and file sink to use for one aggregation
+func (a *Aggregator) currentSettings() (*Config, *FileSink) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+	return a.config, a.fileSink
+}
+
 // GetCachedConfig returns the current cached configuration (thread-safe)
 func (a *Aggregator) GetCachedConfig() HTTPProxyConfig {
 	a.configMutex.RLock()
@@ -31,135 +180,466 @@ func (a *Aggregator) GetCachedConfig() HTTPProxyConfig {
 	return a.cachedConfig
 }
 
+// SetLogger replaces the logger used by the aggregator. By default the aggregator
+// logs to slog.Default() as it was when NewAggregator was called.
+func (a *Aggregator) SetLogger(logger *slog.Logger) {
+	a.logger.Store(logger)
+}
+
+func (a *Aggregator) log() *slog.Logger {
+	return a.logger.Load()
+}
+
+// Metrics returns the metrics collected by the aggregator
+func (a *Aggregator) Metrics() *Metrics {
+	return a.metrics
+}
+
+// RouterCounts returns the number of routers fetched from each downstream during the
+// last successful fetch, keyed by downstream name.
+func (a *Aggregator) RouterCounts() map[string]int {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	counts := make(map[string]int, len(a.downstream))
+	for name, state := range a.downstream {
+		if !state.lastSuccess.IsZero() {
+			counts[name] = state.fetched
+		}
+	}
+	return counts
+}
+
+// DownstreamErrors returns the error of every downstream whose latest fetch failed,
+// keyed by downstream name. Downstreams served from stale configuration are included.
+func (a *Aggregator) DownstreamErrors() map[string]error {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	errs := make(map[string]error)
+	for name, state := range a.downstream {
+		if state.lastErr != nil {
+			errs[name] = state.lastErr
+		}
+	}
+	return errs
+}
+
+// Reasons a downstream router is not promoted upstream. Routers excluded by
+// include_status are skipped as "status_" followed by their status.
+const (
+	skipReasonIgnoredEntryPoint = "ignored_entrypoint"
+	skipReasonNoBackend         = "no_backend"
+	skipReasonStatusPrefix      = "status_"
+)
+
+// downstreamStats counts what happened to the routers of a downstream during one fetch
+type downstreamStats struct {
+	fetched        int
+	skipped        map[string]int // keyed by skip reason
+	skippedRouters []SkippedRouter
+}
+
+func newDownstreamStats() *downstreamStats {
+	return &downstreamStats{skipped: make(map[string]int)}
+}
+
+// skip records a router that was not promoted for the given reason
+func (s *downstreamStats) skip(router TraefikRouter, reason string) {
+	s.skipped[reason]++
+	s.skippedRouters = append(s.skippedRouters, SkippedRouter{Name: router.Name, Reason: reason, Errors: router.Error})
+}
+
+// downstreamResult is the outcome of fetching and converting a single downstream
+type downstreamResult struct {
+	config    HTTPProxyConfig
+	stats     *downstreamStats
+	startedAt time.Time
+	duration  time.Duration
+	attempts  int
+	err       error
+}
+
 // AggregateConfigs fetches router configurations from all downstream Traefik instances
-// and builds a unified HTTPProxyConfig. Errors from individual downstreams are logged
-// but don't stop processing of other downstreams.
-func (a *Aggregator) AggregateConfigs() {
-	newConfig := HTTPProxyConfig{}
-	newConfig.HTTP.Routers = make(map[string]HTTPRouter)
-	newConfig.HTTP.Services = make(map[string]HTTPService)
-	newConfig.HTTP.Middlewares = make(map[string]interface{})
-
-	for _, ds := range a.config.Downstream {
-		// Handle passthrough mode - fetch full config and merge with prefixed names
-		if ds.Passthrough {
-			passthroughConfig, err := FetchPassthroughConfig(ds, a.httpClient)
-			if err != nil {
-				log.Printf("Error fetching passthrough from %s: %v", ds.Name, err)
-				continue
-			}
+// and builds a unified HTTPProxyConfig. Downstreams are fetched in parallel, bounded by
+// max_concurrency, each with its own timeout. Errors from individual downstreams are logged
+// but don't stop processing of other downstreams. A downstream that fails to fetch keeps
+// contributing its last known good configuration until its stale_ttl expires.
+// If ctx is cancelled while fetching, the cached configuration is left untouched.
+func (a *Aggregator) AggregateConfigs(ctx context.Context) {
+	a.aggregate(ctx, false)
+}
 
-			// Merge middlewares with prefixed names
-			for name, middleware := range passthroughConfig.HTTP.Middlewares {
-				prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
-				newConfig.HTTP.Middlewares[prefixedName] = middleware
-			}
+// aggregate runs one aggregation. With dueOnly, downstreams whose poll interval has not
+// elapsed yet are not fetched and contribute the same configuration as last time.
+func (a *Aggregator) aggregate(ctx context.Context, dueOnly bool) {
+	start := time.Now()
+	config, fileSink := a.currentSettings()
+	due := a.dueDownstreams(config, start, dueOnly)
+	results := a.fetchAll(ctx, config, due)
 
-			// Merge routers with prefixed names
-			for name, router := range passthroughConfig.HTTP.Routers {
-				prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
-				prefixedServiceName := fmt.Sprintf("%s-%s", ds.Name, router.Service)
-				router.Service = prefixedServiceName
-
-				// Prefix middleware references
-				if len(router.Middlewares) > 0 {
-					prefixedMiddlewares := make([]string, len(router.Middlewares))
-					for i, mw := range router.Middlewares {
-						prefixedMiddlewares[i] = fmt.Sprintf("%s-%s", ds.Name, mw)
-					}
-					router.Middlewares = prefixedMiddlewares
-				}
-
-				newConfig.HTTP.Routers[prefixedName] = router
-			}
+	if ctx.Err() != nil {
+		a.log().Info("Config aggregation cancelled", "error", ctx.Err())
+		return
+	}
 
-			// Merge services with prefixed names
-			for name, service := range passthroughConfig.HTTP.Services {
-				prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
-				newConfig.HTTP.Services[prefixedName] = service
-			}
+	newConfig := newHTTPProxyConfig()
 
-			log.Printf("Passthrough %s: %d routers, %d services, %d middlewares",
-				ds.Name,
-				len(passthroughConfig.HTTP.Routers),
-				len(passthroughConfig.HTTP.Services),
-				len(passthroughConfig.HTTP.Middlewares))
+	// Merge in configuration order so name collisions resolve deterministically
+	for i, ds := range config.Downstream {
+		result := results[i]
+		if !due[i] {
+			mergeConfig(&newConfig, result.config)
 			continue
 		}
 
-		routers, err := FetchDownstreamRouters(ds, a.httpClient)
-		if err != nil {
-			log.Printf("Error fetching from %s: %v", ds.Name, err)
+		nextPoll := start.Add(config.DownstreamPollInterval(ds))
+		a.metrics.observeFetch(ds.Name, result)
+		if result.err != nil {
+			if errors.Is(result.err, ErrCircuitOpen) {
+				a.log().Debug("Skipping downstream", "downstream", ds.Name, "error", result.err)
+			} else {
+				a.log().Error("Error fetching downstream", "downstream", ds.Name,
+					"attempts", result.attempts, "error", result.err)
+			}
+			contribution := newHTTPProxyConfig()
+			if snapshot, ok := a.staleSnapshot(ds); ok {
+				a.log().Warn("Serving stale config", "downstream", ds.Name,
+					"age", time.Since(snapshot.fetchedAt).Round(time.Second).String())
+				contribution = snapshot.config
+				mergeConfig(&newConfig, contribution)
+			}
+			a.recordFetch(ds, result, contribution, nextPoll)
 			continue
 		}
 
-		log.Printf("Processing %s with %d routers", ds.Name, len(routers))
+		a.recordFetch(ds, result, result.config, nextPoll)
+		a.storeSnapshot(ds, result.config)
+		mergeConfig(&newConfig, result.config)
+	}
 
-		for _, router := range routers {
-			// Skip routers with ignored entrypoints
-			if ShouldIgnoreRouter(router, ds.IgnoreEntryPoints) {
-				log.Printf("  Skipping router %s (ignored entrypoint)", router.Name)
-				continue
-			}
+	// Middlewares defined in the configuration file win over downstream ones of the same name
+	for name, middleware := range config.Middlewares {
+		if _, ok := newConfig.HTTP.Middlewares[name]; ok {
+			a.log().Warn("Configured middleware replaces a downstream middleware", "middleware", name)
+		}
+		newConfig.HTTP.Middlewares[name] = middleware
+	}
 
-			// Determine if this router uses TLS
-			useTLS := len(router.TLS) > 0
+	a.setCachedConfig(newConfig)
+	a.metrics.observeAggregation(time.Since(start))
+	a.recordAggregation(config, results)
 
-			// Get backend URL with protocol matching
-			backendURL := GetBackendURL(ds, useTLS)
+	a.log().Info("Config aggregation complete",
+		"routers", len(newConfig.HTTP.Routers),
+		"services", len(newConfig.HTTP.Services),
+		"tcp_routers", len(newConfig.TCP.Routers),
+		"udp_routers", len(newConfig.UDP.Routers),
+		"duration", time.Since(start).String())
 
-			// Generate unique names for router and service
-			// Use router name without provider suffix if available
-			routerBaseName := router.Name
-			if idx := strings.Index(routerBaseName, "@"); idx != -1 {
-				routerBaseName = routerBaseName[:idx]
-			}
+	if fileSink != nil {
+		written, err := fileSink.Write(newConfig)
+		if err != nil {
+			a.log().Error("Error writing output file", "path", fileSink.Path(), "error", err)
+		} else if written {
+			a.log().Info("Wrote aggregated config", "path", fileSink.Path())
+		}
+	}
+}
 
-			httpRouterName := fmt.Sprintf("%s-%s", ds.Name, routerBaseName)
-			httpServiceName := fmt.Sprintf("service-%s-%s", ds.Name, routerBaseName)
+// fetchAll fetches all downstreams concurrently using a bounded worker pool and returns
+// their results in configuration order.
+func (a *Aggregator) fetchAll(ctx context.Context, config *Config, due []bool) []downstreamResult {
+	downstreams := config.Downstream
+	results := make([]downstreamResult, len(downstreams))
 
-			// Determine entrypoints - use override if specified
-			entryPoints := router.EntryPoints
-			if len(ds.EntryPoints) > 0 {
-				entryPoints = ds.EntryPoints
+	sem := make(chan struct{}, config.MaxConcurrencyLimit())
+	var wg sync.WaitGroup
+
+	for i, ds := range downstreams {
+		if !due[i] {
+			results[i] = a.previousResult(ds)
+			continue
+		}
+		if until := a.circuitOpenUntil(ds); !until.IsZero() {
+			results[i] = downstreamResult{err: circuitOpenError(until)}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = downstreamResult{err: ctx.Err()}
+				return
 			}
 
-			// Create HTTP router preserving original rule
-			httpRouter := HTTPRouter{
-				Rule:        router.Rule,
-				Service:     httpServiceName,
-				EntryPoints: entryPoints,
-				Middlewares: ds.Middlewares, // User-defined middlewares from config
+			dsCtx := ctx
+			if timeout := ds.TimeoutDuration(); timeout > 0 {
+				var cancel context.CancelFunc
+				dsCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
 			}
 
-			// Build TLS config with domain extraction
-			if ds.TLS != nil || len(router.TLS) > 0 {
-				tlsConfig := BuildTLSConfig(ds, router.Rule, router.TLS)
-				if len(tlsConfig) > 0 {
-					httpRouter.TLS = tlsConfig
-				}
+			fetchStart := time.Now()
+			config, stats, attempts, err := a.fetchWithRetry(dsCtx, ds)
+			results[i] = downstreamResult{
+				config:    config,
+				stats:     stats,
+				startedAt: fetchStart,
+				duration:  time.Since(fetchStart),
+				attempts:  attempts,
+				err:       err,
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// buildDownstreamConfig fetches a single downstream and converts it into the
+// configuration fragment it contributes to the aggregated output.
+func (a *Aggregator) buildDownstreamConfig(ctx context.Context, ds DownstreamConfig) (HTTPProxyConfig, *downstreamStats, error) {
+	if ds.Passthrough {
+		return a.buildPassthroughConfig(ctx, ds)
+	}
+	return a.buildRouterConfig(ctx, ds)
+}
+
+// buildPassthroughConfig fetches a full config from a passthrough downstream and
+// prefixes all router, service and middleware names with the downstream name.
+func (a *Aggregator) buildPassthroughConfig(ctx context.Context, ds DownstreamConfig) (HTTPProxyConfig, *downstreamStats, error) {
+	newConfig := newHTTPProxyConfig()
+	stats := newDownstreamStats()
+
+	passthroughConfig, err := FetchPassthroughConfig(ctx, ds, a.client())
+	if err != nil {
+		return newConfig, stats, fmt.Errorf("passthrough: %w", err)
+	}
+	stats.fetched = len(passthroughConfig.HTTP.Routers) + len(passthroughConfig.TCP.Routers) + len(passthroughConfig.UDP.Routers)
+
+	// Merge middlewares with prefixed names
+	for name, middleware := range passthroughConfig.HTTP.Middlewares {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		newConfig.HTTP.Middlewares[prefixedName] = middleware
+	}
+
+	// Merge routers with prefixed names
+	for name, router := range passthroughConfig.HTTP.Routers {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		prefixedServiceName := fmt.Sprintf("%s-%s", ds.Name, router.Service)
+		router.Service = prefixedServiceName
+
+		// Prefix middleware references
+		if len(router.Middlewares) > 0 {
+			prefixedMiddlewares := make([]string, len(router.Middlewares))
+			for i, mw := range router.Middlewares {
+				prefixedMiddlewares[i] = fmt.Sprintf("%s-%s", ds.Name, mw)
 			}
+			router.Middlewares = prefixedMiddlewares
+		}
+
+		newConfig.HTTP.Routers[prefixedName] = router
+	}
+
+	// Merge services with prefixed names
+	for name, service := range passthroughConfig.HTTP.Services {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		newConfig.HTTP.Services[prefixedName] = service
+	}
+
+	// Merge TCP routers and services with prefixed names
+	for name, router := range passthroughConfig.TCP.Routers {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		router.Service = fmt.Sprintf("%s-%s", ds.Name, router.Service)
+		newConfig.TCP.Routers[prefixedName] = router
+	}
+	for name, service := range passthroughConfig.TCP.Services {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		newConfig.TCP.Services[prefixedName] = service
+	}
+
+	// Merge UDP routers and services with prefixed names
+	for name, router := range passthroughConfig.UDP.Routers {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		router.Service = fmt.Sprintf("%s-%s", ds.Name, router.Service)
+		newConfig.UDP.Routers[prefixedName] = router
+	}
+	for name, service := range passthroughConfig.UDP.Services {
+		prefixedName := fmt.Sprintf("%s-%s", ds.Name, name)
+		newConfig.UDP.Services[prefixedName] = service
+	}
+
+	a.log().Debug("Processed passthrough downstream",
+		"downstream", ds.Name,
+		"routers", len(passthroughConfig.HTTP.Routers),
+		"services", len(passthroughConfig.HTTP.Services),
+		"middlewares", len(passthroughConfig.HTTP.Middlewares),
+		"tcp_routers", len(passthroughConfig.TCP.Routers),
+		"udp_routers", len(passthroughConfig.UDP.Routers))
+
+	return newConfig, stats, nil
+}
+
+// buildRouterConfig fetches the routers of a downstream Traefik and generates an
+// upstream router and service pointing back at the downstream for each of them.
+func (a *Aggregator) buildRouterConfig(ctx context.Context, ds DownstreamConfig) (HTTPProxyConfig, *downstreamStats, error) {
+	newConfig := newHTTPProxyConfig()
+	stats := newDownstreamStats()
+
+	routers, err := FetchDownstreamRouters(ctx, ds, a.client())
+	if err != nil {
+		return newConfig, stats, err
+	}
+
+	var tcpRouters []TraefikRouter
+	if ds.TCP != nil && ds.TCP.Enabled {
+		tcpRouters, err = FetchDownstreamTCPRouters(ctx, ds, a.client())
+		if err != nil {
+			return newConfig, stats, fmt.Errorf("tcp routers: %w", err)
+		}
+	}
+
+	var udpRouters []TraefikRouter
+	if ds.UDP != nil && ds.UDP.Enabled {
+		udpRouters, err = FetchDownstreamUDPRouters(ctx, ds, a.client())
+		if err != nil {
+			return newConfig, stats, fmt.Errorf("udp routers: %w", err)
+		}
+	}
+
+	var middlewares []TraefikMiddleware
+	if ds.Replicate != nil && ds.Replicate.Enabled {
+		middlewares, err = FetchDownstreamMiddlewares(ctx, ds, a.client())
+		if err != nil {
+			return newConfig, stats, fmt.Errorf("middlewares: %w", err)
+		}
+	}
+
+	a.log().Debug("Processing downstream",
+		"downstream", ds.Name,
+		"routers", len(routers),
+		"tcp_routers", len(tcpRouters),
+		"udp_routers", len(udpRouters),
+		"middlewares", len(middlewares))
+
+	stats.fetched = len(routers) + len(tcpRouters) + len(udpRouters)
+
+	a.addTCPRouters(&newConfig, ds, tcpRouters, stats)
+	a.addUDPRouters(&newConfig, ds, udpRouters, stats)
+	replicated := a.addReplicatedMiddlewares(&newConfig, ds, middlewares)
+
+	for _, router := range routers {
+		if reason := routerSkipReason(ds, router); reason != "" {
+			a.log().Debug("Skipping router", "downstream", ds.Name, "router", router.Name, "reason", reason, "errors", router.Error)
+			stats.skip(router, reason)
+			continue
+		}
+
+		// Determine if this router uses TLS
+		useTLS := len(router.TLS) > 0
+
+		// Get backend URL with protocol matching
+		backendURL := GetBackendURL(ds, useTLS)
+
+		// Generate unique names for router and service
+		// Use router name without provider suffix if available
+		routerBaseName := router.Name
+		if idx := strings.Index(routerBaseName, "@"); idx != -1 {
+			routerBaseName = routerBaseName[:idx]
+		}
 
-			newConfig.HTTP.Routers[httpRouterName] = httpRouter
+		httpRouterName := fmt.Sprintf("%s-%s", ds.Name, routerBaseName)
+		httpServiceName := fmt.Sprintf("service-%s-%s", ds.Name, routerBaseName)
 
-			// Create HTTP service pointing to downstream Traefik
-			httpService := HTTPService{}
-			httpService.LoadBalancer.Servers = []Server{
-				{URL: backendURL},
+		// Determine entrypoints - use override if specified
+		entryPoints := router.EntryPoints
+		if len(ds.EntryPoints) > 0 {
+			entryPoints = ds.EntryPoints
+		}
+
+		// In TLS passthrough mode, TLS routers become TCP HostSNI routers
+		if useTLS && ds.TLS != nil && ds.TLS.Passthrough {
+			if a.addSNIPassthroughRouter(&newConfig, ds, router, routerBaseName, entryPoints) {
+				continue
 			}
-			if ds.ServerTransport != "" {
-				httpService.LoadBalancer.ServersTransport = ds.ServerTransport
+		}
+
+		// Create HTTP router preserving original rule
+		httpRouter := HTTPRouter{
+			Rule:        router.Rule,
+			Service:     httpServiceName,
+			EntryPoints: entryPoints,
+			Priority:    RouterPriority(ds, router),
+			Middlewares: RouterMiddlewares(ds, router, replicated),
+		}
+
+		// Build TLS config with domain extraction
+		if ds.TLS != nil || len(router.TLS) > 0 {
+			tlsConfig := BuildTLSConfig(ds, router.Rule, router.TLS)
+			if len(tlsConfig) > 0 {
+				httpRouter.TLS = tlsConfig
 			}
-			newConfig.HTTP.Services[httpServiceName] = httpService
+		}
+
+		newConfig.HTTP.Routers[httpRouterName] = httpRouter
 
-			log.Printf("  Added HTTP route: %s -> %s (TLS: %v)", router.Rule, backendURL, useTLS)
+		// Create HTTP service pointing to downstream Traefik
+		httpService := HTTPService{}
+		httpService.LoadBalancer.Servers = []Server{
+			{URL: backendURL},
 		}
+		if ds.ServerTransport != "" {
+			httpService.LoadBalancer.ServersTransport = ds.ServerTransport
+		}
+		newConfig.HTTP.Services[httpServiceName] = httpService
+
+		a.log().Debug("Added HTTP route", "downstream", ds.Name, "router", router.Name,
+			"rule", router.Rule, "backend", backendURL, "tls", useTLS)
 	}
 
-	a.configMutex.Lock()
-	a.cachedConfig = newConfig
-	a.configMutex.Unlock()
+	return newConfig, stats, nil
+}
+
+// newHTTPProxyConfig returns an empty HTTPProxyConfig with all maps initialized
+func newHTTPProxyConfig() HTTPProxyConfig {
+	config := HTTPProxyConfig{}
+	config.HTTP.Routers = make(map[string]HTTPRouter)
+	config.HTTP.Services = make(map[string]HTTPService)
+	config.HTTP.Middlewares = make(map[string]interface{})
+	config.TCP.Routers = make(map[string]TCPRouter)
+	config.TCP.Services = make(map[string]TCPService)
+	config.UDP.Routers = make(map[string]UDPRouter)
+	config.UDP.Services = make(map[string]UDPService)
+	return config
+}
 
-	log.Printf("Config aggregation complete: %d routers, %d services",
-		len(newConfig.HTTP.Routers), len(newConfig.HTTP.Services))
+// mergeConfig copies all routers, services and middlewares from src into dst
+func mergeConfig(dst *HTTPProxyConfig, src HTTPProxyConfig) {
+	for name, router := range src.HTTP.Routers {
+		dst.HTTP.Routers[name] = router
+	}
+	for name, service := range src.HTTP.Services {
+		dst.HTTP.Services[name] = service
+	}
+	for name, middleware := range src.HTTP.Middlewares {
+		dst.HTTP.Middlewares[name] = middleware
+	}
+	for name, router := range src.TCP.Routers {
+		dst.TCP.Routers[name] = router
+	}
+	for name, service := range src.TCP.Services {
+		dst.TCP.Services[name] = service
+	}
+	for name, router := range src.UDP.Routers {
+		dst.UDP.Routers[name] = router
+	}
+	for name, service := range src.UDP.Services {
+		dst.UDP.Services[name] = service
+	}
 }
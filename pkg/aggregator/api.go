@@ -1,34 +1,96 @@
 package aggregator
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
-const maxErrorBodyLen = 256
+const (
+	maxErrorBodyLen = 256
+
+	// routersPerPage is the page size requested from the Traefik API
+	routersPerPage = 100
+	// maxRouterPages guards against downstreams that never stop returning a next page
+	maxRouterPages = 1000
+)
 
 // FetchDownstreamRouters fetches router configurations from a downstream Traefik API.
-func FetchDownstreamRouters(ds DownstreamConfig, client *http.Client) ([]TraefikRouter, error) {
-	apiEndpoint, err := url.JoinPath(ds.APIURL, "/api/http/routers")
+// The request is cancelled when ctx is done. The Traefik API paginates its results, so all pages are followed using the
+// X-Next-Page response header until the last page has been read.
+func FetchDownstreamRouters(ctx context.Context, ds DownstreamConfig, client *http.Client) ([]TraefikRouter, error) {
+	return fetchPaginated[TraefikRouter](ctx, ds, client, "/api/http/routers")
+}
+
+// FetchDownstreamTCPRouters fetches TCP router configurations from a downstream Traefik API,
+// following pagination the same way as FetchDownstreamRouters.
+func FetchDownstreamTCPRouters(ctx context.Context, ds DownstreamConfig, client *http.Client) ([]TraefikRouter, error) {
+	return fetchPaginated[TraefikRouter](ctx, ds, client, "/api/tcp/routers")
+}
+
+// FetchDownstreamUDPRouters fetches UDP router configurations from a downstream Traefik API,
+// following pagination the same way as FetchDownstreamRouters.
+func FetchDownstreamUDPRouters(ctx context.Context, ds DownstreamConfig, client *http.Client) ([]TraefikRouter, error) {
+	return fetchPaginated[TraefikRouter](ctx, ds, client, "/api/udp/routers")
+}
+
+// FetchDownstreamMiddlewares fetches HTTP middleware definitions from a downstream Traefik API,
+// following pagination the same way as FetchDownstreamRouters.
+func FetchDownstreamMiddlewares(ctx context.Context, ds DownstreamConfig, client *http.Client) ([]TraefikMiddleware, error) {
+	return fetchPaginated[TraefikMiddleware](ctx, ds, client, "/api/http/middlewares")
+}
+
+// fetchPaginated walks all pages of a list endpoint of the Traefik API and returns
+// the concatenated items.
+func fetchPaginated[T any](ctx context.Context, ds DownstreamConfig, client *http.Client, path string) ([]T, error) {
+	apiEndpoint, err := url.JoinPath(ds.APIURL, path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid API URL: %w", err)
 	}
 
-	req, err := http.NewRequest("GET", apiEndpoint, nil)
+	var items []T
+	page := 1
+	for fetched := 0; fetched < maxRouterPages; fetched++ {
+		pageItems, nextPage, err := fetchPage[T](ctx, ds, client, apiEndpoint, page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		items = append(items, pageItems...)
+
+		// Traefik reports next page 1 (or omits the header) on the last page
+		if nextPage <= page {
+			return items, nil
+		}
+		page = nextPage
+	}
+
+	return nil, fmt.Errorf("exceeded %d pages fetching %s", maxRouterPages, path)
+}
+
+// fetchPage fetches a single page from a Traefik API list endpoint and returns its items
+// together with the page number announced in the X-Next-Page header (0 if absent).
+func fetchPage[T any](ctx context.Context, ds DownstreamConfig, client *http.Client, apiEndpoint string, page int) ([]T, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiEndpoint, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
+	query := req.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(routersPerPage))
+	req.URL.RawQuery = query.Encode()
+
 	if ds.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+ds.APIKey)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
@@ -38,22 +100,30 @@ func FetchDownstreamRouters(ds DownstreamConfig, client *http.Client) ([]Traefik
 		if len(bodyStr) > maxErrorBodyLen {
 			bodyStr = bodyStr[:maxErrorBodyLen] + "...(truncated)"
 		}
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, bodyStr)
+		return nil, 0, fmt.Errorf("API returned status %d: %s", resp.StatusCode, bodyStr)
 	}
 
 	// Traefik API returns an array, not a map
-	var routersArray []TraefikRouter
-	if err := json.NewDecoder(resp.Body).Decode(&routersArray); err != nil {
-		return nil, err
+	var items []T
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, 0, err
+	}
+
+	nextPage := 0
+	if header := resp.Header.Get("X-Next-Page"); header != "" {
+		nextPage, err = strconv.Atoi(header)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid X-Next-Page header %q", header)
+		}
 	}
 
-	return routersArray, nil
+	return items, nextPage, nil
 }
 
 // FetchPassthroughConfig fetches a full HTTPProxyConfig from a passthrough downstream.
 // Unlike FetchDownstreamRouters, this fetches directly from the api_url without appending a path.
-func FetchPassthroughConfig(ds DownstreamConfig, client *http.Client) (*HTTPProxyConfig, error) {
-	req, err := http.NewRequest("GET", ds.APIURL, nil)
+func FetchPassthroughConfig(ctx context.Context, ds DownstreamConfig, client *http.Client) (*HTTPProxyConfig, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", ds.APIURL, nil)
 	if err != nil {
 		return nil, err
 	}
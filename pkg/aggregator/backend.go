@@ -1,6 +1,7 @@
 package aggregator
 
 import (
+	"net"
 	"strings"
 )
 
@@ -45,3 +46,114 @@ func GetBackendURL(ds DownstreamConfig, useTLS bool) string {
 
 	return protocol + apiURL
 }
+
+// GetTCPBackendAddress determines the host:port address TCP services should target.
+// If a TCP BackendOverride is set, it's used (with any scheme removed). Otherwise
+// the address is derived from GetBackendURL without its protocol and path. Addresses
+// without a port get 443 with TLS and 80 without.
+func GetTCPBackendAddress(ds DownstreamConfig, useTLS bool) string {
+	address := GetBackendURL(ds, useTLS)
+	if ds.TCP != nil && ds.TCP.BackendOverride != "" {
+		address = ds.TCP.BackendOverride
+	}
+
+	defaultPort := "80"
+	if useTLS {
+		defaultPort = "443"
+	}
+	return hostPort(address, defaultPort)
+}
+
+// hostPort reduces a URL or address to host:port, dropping any scheme and path and
+// adding defaultPort when no port is present
+func hostPort(address, defaultPort string) string {
+	address = strings.TrimPrefix(address, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	if idx := strings.Index(address, "/"); idx != -1 {
+		address = address[:idx]
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, defaultPort)
+	}
+	return address
+}
+
+// GetTCPRouterBackendAddress determines the host:port address the service of a TCP
+// router on the given downstream entrypoints should target. The first entrypoint
+// listed in tcp.backends wins; ":port" values target that port on the host of
+// GetTCPBackendAddress. Otherwise a TCP BackendOverride is used, and TLS routers fall
+// back to the downstream's HTTPS backend. Returns false for other non-TLS routers,
+// since the downstream's HTTP port only serves them on its web entrypoint.
+func GetTCPRouterBackendAddress(ds DownstreamConfig, entryPoints []string, useTLS bool) (string, bool) {
+	address := GetTCPBackendAddress(ds, useTLS)
+	if ds.TCP == nil {
+		return address, useTLS
+	}
+	if backend, ok := entryPointBackend(ds.TCP.Backends, entryPoints, address); ok {
+		return backend, true
+	}
+	return address, useTLS || ds.TCP.BackendOverride != ""
+}
+
+// entryPointBackend looks up the first of entryPoints in backends. A ":port" value is
+// completed with the host of defaultAddress.
+func entryPointBackend(backends map[string]string, entryPoints []string, defaultAddress string) (string, bool) {
+	for _, ep := range entryPoints {
+		backend, ok := backends[ep]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(backend, ":") {
+			host := defaultAddress
+			if h, _, err := net.SplitHostPort(defaultAddress); err == nil {
+				host = h
+			}
+			return net.JoinHostPort(host, backend[1:]), true
+		}
+		return backend, true
+	}
+	return "", false
+}
+
+// GetUDPBackendAddress determines the host:port address the service of a UDP router
+// on the given downstream entrypoints should target. The first entrypoint listed in
+// udp.backends wins; ":port" values target that port on the downstream host (taken
+// from udp.backend_override if set, otherwise from the backend URL). Otherwise a UDP
+// BackendOverride is used. Returns false when neither applies, since the downstream's
+// HTTP port never serves UDP.
+func GetUDPBackendAddress(ds DownstreamConfig, entryPoints []string) (string, bool) {
+	if ds.UDP == nil {
+		return "", false
+	}
+
+	address := GetBackendURL(ds, false)
+	if ds.UDP.BackendOverride != "" {
+		address = ds.UDP.BackendOverride
+	}
+	address = hostPort(address, "80")
+
+	if backend, ok := entryPointBackend(ds.UDP.Backends, entryPoints, address); ok {
+		return backend, true
+	}
+	if ds.UDP.BackendOverride != "" {
+		return address, true
+	}
+	return "", false
+}
+
+// GetSNIPassthroughAddress determines the host:port address TLS passthrough TCP services
+// should target. A TCP BackendOverride is used as-is; otherwise the downstream host from
+// backend_override or api_url is combined with port 443, since neither the API port nor
+// an HTTP backend port serves passthrough TLS traffic.
+func GetSNIPassthroughAddress(ds DownstreamConfig) string {
+	address := GetTCPBackendAddress(ds, true)
+	if ds.TCP != nil && ds.TCP.BackendOverride != "" {
+		return address
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	return net.JoinHostPort(host, "443")
+}
@@ -0,0 +1,163 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 200 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultCooldown       = 5 * time.Minute
+)
+
+// ErrCircuitOpen is reported for a downstream that was not fetched because its
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Circuit breaker states reported in the downstream status
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// RetryAttempts returns how many times the downstream is fetched per poll cycle before
+// giving up. Defaults to 1, i.e. no retries.
+func (ds DownstreamConfig) RetryAttempts() int {
+	if ds.Retry == nil || ds.Retry.Attempts < 1 {
+		return 1
+	}
+	return ds.Retry.Attempts
+}
+
+// RetryBackoff returns the wait before the given retry (1 for the first retry).
+// The backoff doubles with every retry up to max_backoff, and a random jitter of up
+// to half the backoff is subtracted so that retries of many downstreams spread out.
+func (ds DownstreamConfig) RetryBackoff(retry int) time.Duration {
+	initial, maxBackoff := defaultInitialBackoff, defaultMaxBackoff
+	if ds.Retry != nil {
+		initial = parseDurationOr(ds.Retry.InitialBackoff, initial)
+		maxBackoff = parseDurationOr(ds.Retry.MaxBackoff, maxBackoff)
+	}
+
+	backoff := initial
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+
+	return backoff - rand.N(backoff/2+1)
+}
+
+// CircuitCooldown returns how long the circuit breaker stays open. Defaults to 5m.
+func (ds DownstreamConfig) CircuitCooldown() time.Duration {
+	if ds.CircuitBreaker == nil {
+		return defaultCooldown
+	}
+	return parseDurationOr(ds.CircuitBreaker.Cooldown, defaultCooldown)
+}
+
+// circuitThreshold returns the consecutive failures that open the circuit, 0 if disabled
+func (ds DownstreamConfig) circuitThreshold() int {
+	if ds.CircuitBreaker == nil || ds.CircuitBreaker.FailureThreshold < 0 {
+		return 0
+	}
+	return ds.CircuitBreaker.FailureThreshold
+}
+
+// parseDurationOr parses a positive duration, falling back to def when unset or invalid
+func parseDurationOr(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return def
+	}
+	return duration
+}
+
+// fetchWithRetry builds the downstream configuration, retrying failed attempts with
+// backoff until the attempts are used up or ctx is done.
+func (a *Aggregator) fetchWithRetry(ctx context.Context, ds DownstreamConfig) (HTTPProxyConfig, *downstreamStats, int, error) {
+	attempts := ds.RetryAttempts()
+	for attempt := 1; ; attempt++ {
+		config, stats, err := a.buildDownstreamConfig(ctx, ds)
+		if err == nil || attempt >= attempts || ctx.Err() != nil {
+			return config, stats, attempt, err
+		}
+
+		backoff := ds.RetryBackoff(attempt)
+		a.log().Debug("Retrying downstream", "downstream", ds.Name,
+			"attempt", attempt+1, "backoff", backoff.String(), "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return config, stats, attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// circuitOpenUntil returns when the open circuit breaker of a downstream allows the
+// next attempt, or the zero time if the downstream may be fetched now
+func (a *Aggregator) circuitOpenUntil(ds DownstreamConfig) time.Time {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	state, ok := a.downstream[ds.Name]
+	if !ok || !time.Now().Before(state.openUntil) {
+		return time.Time{}
+	}
+	return state.openUntil
+}
+
+// updateCircuit tracks consecutive failures and opens or closes the circuit breaker.
+// Must be called with stateMutex held.
+func (a *Aggregator) updateCircuit(ds DownstreamConfig, state *downstreamState, err error) {
+	if errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+
+	if err == nil {
+		if !state.openUntil.IsZero() {
+			a.log().Info("Circuit breaker closed", "downstream", ds.Name)
+		}
+		state.consecutiveFailures = 0
+		state.openUntil = time.Time{}
+		return
+	}
+
+	state.consecutiveFailures++
+	threshold := ds.circuitThreshold()
+	if threshold > 0 && state.consecutiveFailures >= threshold {
+		cooldown := ds.CircuitCooldown()
+		state.openUntil = time.Now().Add(cooldown)
+		a.log().Warn("Circuit breaker opened", "downstream", ds.Name,
+			"consecutive_failures", state.consecutiveFailures, "cooldown", cooldown.String())
+	}
+}
+
+// circuitState describes the circuit breaker of a downstream for the status output.
+// Must be called with stateMutex held.
+func circuitState(state *downstreamState) string {
+	switch {
+	case state == nil || state.openUntil.IsZero():
+		return CircuitClosed
+	case time.Now().Before(state.openUntil):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// circuitOpenError wraps ErrCircuitOpen with the time the next attempt is allowed
+func circuitOpenError(until time.Time) error {
+	return fmt.Errorf("%w until %s", ErrCircuitOpen, until.UTC().Format(time.RFC3339))
+}
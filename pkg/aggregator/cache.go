@@ -0,0 +1,71 @@
+package aggregator
+
+import (
+	"sort"
+	"time"
+)
+
+// downstreamSnapshot is the last configuration fragment successfully built for a downstream
+type downstreamSnapshot struct {
+	config    HTTPProxyConfig
+	fetchedAt time.Time
+}
+
+// StaleTTLDuration returns how long the last known good configuration of a downstream may be
+// served after fetches start failing. Zero means stale configuration is never served.
+func (ds DownstreamConfig) StaleTTLDuration() time.Duration {
+	if ds.StaleTTL == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(ds.StaleTTL)
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// storeSnapshot records a freshly built configuration as the downstream's last known good state
+func (a *Aggregator) storeSnapshot(ds DownstreamConfig, config HTTPProxyConfig) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	a.lastGood[ds.Name] = downstreamSnapshot{config: config, fetchedAt: time.Now()}
+	delete(a.stale, ds.Name)
+}
+
+// staleSnapshot returns the last known good configuration of a downstream if it is
+// still within the downstream's stale_ttl, marking the downstream as stale.
+// Expired snapshots are dropped.
+func (a *Aggregator) staleSnapshot(ds DownstreamConfig) (downstreamSnapshot, bool) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	snapshot, ok := a.lastGood[ds.Name]
+	if !ok {
+		delete(a.stale, ds.Name)
+		return downstreamSnapshot{}, false
+	}
+
+	if time.Since(snapshot.fetchedAt) > ds.StaleTTLDuration() {
+		delete(a.lastGood, ds.Name)
+		delete(a.stale, ds.Name)
+		return downstreamSnapshot{}, false
+	}
+
+	a.stale[ds.Name] = true
+	return snapshot, true
+}
+
+// StaleDownstreams returns the sorted names of downstreams currently served from
+// their last known good configuration because the latest fetch failed.
+func (a *Aggregator) StaleDownstreams() []string {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	names := make([]string, 0, len(a.stale))
+	for name := range a.stale {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,12 +1,26 @@
 package aggregator
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig loads the application configuration from the specified YAML file.
+// defaultPollInterval is used when poll_interval is not set or invalid
+const defaultPollInterval = 30 * time.Second
+
+// defaultMaxConcurrency is the number of downstreams fetched in parallel when
+// max_concurrency is not set
+const defaultMaxConcurrency = 8
+
+// LoadConfig loads the application configuration from the specified YAML file and
+// validates it. Unknown keys and every problem found by Validate are reported together
+// as ValidationErrors carrying the YAML line of the offending setting.
 // If poll_interval is not specified, defaults to 30s.
 func LoadConfig(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
@@ -14,15 +28,85 @@ func LoadConfig(filename string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, err
 	}
 
+	var config Config
+	var errs ValidationErrors
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
+		// The rest of the document is still decoded, so keep validating
+		errs = append(errs, decodeErrors(typeErr)...)
+	}
+
+	for _, validationErr := range config.validate() {
+		validationErr.Line = lineOf(&root, validationErr.Field)
+		errs = append(errs, validationErr)
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		return nil, errs
+	}
+
 	if config.PollInterval == "" {
 		config.PollInterval = "30s"
 	}
 
 	return &config, nil
 }
+
+// PollIntervalDuration returns how often downstreams are polled. Defaults to 30s.
+func (c *Config) PollIntervalDuration() time.Duration {
+	interval, err := time.ParseDuration(c.PollInterval)
+	if err != nil || interval <= 0 {
+		return defaultPollInterval
+	}
+	return interval
+}
+
+// DownstreamPollInterval returns how often a downstream is polled: its own
+// poll_interval if set, otherwise the global one.
+func (c *Config) DownstreamPollInterval(ds DownstreamConfig) time.Duration {
+	return parseDurationOr(ds.PollInterval, c.PollIntervalDuration())
+}
+
+// shortestPollInterval returns the interval of the most frequently polled downstream
+func (c *Config) shortestPollInterval() time.Duration {
+	shortest := c.PollIntervalDuration()
+	for i, ds := range c.Downstream {
+		if interval := c.DownstreamPollInterval(ds); i == 0 || interval < shortest {
+			shortest = interval
+		}
+	}
+	return shortest
+}
+
+// MaxConcurrencyLimit returns the number of downstreams that may be fetched in parallel.
+// Defaults to 8 when max_concurrency is not set or invalid.
+func (c *Config) MaxConcurrencyLimit() int {
+	if c.MaxConcurrency <= 0 {
+		return defaultMaxConcurrency
+	}
+	return c.MaxConcurrency
+}
+
+// TimeoutDuration returns the deadline for a single fetch cycle of the downstream.
+// Zero means only the HTTP client timeout applies.
+func (ds DownstreamConfig) TimeoutDuration() time.Duration {
+	if ds.Timeout == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(ds.Timeout)
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
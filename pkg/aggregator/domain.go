@@ -56,3 +56,49 @@ func ExtractDomainsFromRule(rule string, wildcardFix bool) []string {
 
 	return domains
 }
+
+// ExtractSNIDomainsFromRule parses HostSNI() and HostSNIRegexp() patterns from a Traefik
+// TCP rule and returns a list of domains. The catch-all HostSNI(`*`) is not a domain and
+// is skipped. HostSNIRegexp patterns are only processed if wildcardFix is true.
+func ExtractSNIDomainsFromRule(rule string, wildcardFix bool) []string {
+	var domains []string
+
+	// Extract HostSNI(`domain`) patterns
+	hostSNIRegex := regexp.MustCompile("HostSNI\\(`([^`]+)`\\)")
+	for _, match := range hostSNIRegex.FindAllStringSubmatch(rule, -1) {
+		if len(match) > 1 && match[1] != "*" {
+			domains = append(domains, match[1])
+		}
+	}
+
+	// Extract HostSNIRegexp() patterns (only if wildcardFix enabled)
+	if wildcardFix {
+		hostSNIRegexpRegex := regexp.MustCompile("HostSNIRegexp\\(`([^`]+)`\\)")
+		for _, match := range hostSNIRegexpRegex.FindAllStringSubmatch(rule, -1) {
+			if len(match) > 1 {
+				domain := ConvertRegexpToWildcard(match[1])
+				if domain != "" {
+					domains = append(domains, domain)
+				}
+			}
+		}
+	}
+
+	return domains
+}
+
+// BuildHostSNIRule builds a TCP rule matching the given domains. Wildcard domains
+// (as returned by ConvertRegexpToWildcard) are matched with HostSNIRegexp since
+// HostSNI only accepts exact names.
+func BuildHostSNIRule(domains []string) string {
+	matchers := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			suffix := strings.ReplaceAll(strings.TrimPrefix(domain, "*."), ".", `\.`)
+			matchers = append(matchers, "HostSNIRegexp(`^[^.]+\\."+suffix+"$`)")
+			continue
+		}
+		matchers = append(matchers, "HostSNI(`"+domain+"`)")
+	}
+	return strings.Join(matchers, " || ")
+}
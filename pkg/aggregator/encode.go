@@ -0,0 +1,357 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output encoding for the aggregated configuration
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// ParseFormat returns the Format matching name (case-insensitive). "yml" is accepted
+// as an alias for YAML.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (expected json, yaml or toml)", name)
+	}
+}
+
+// FormatFromAccept picks an output format from an HTTP Accept header: the supported
+// media type with the highest q-value wins, ties going to the one listed first. Types
+// with q=0 are not acceptable and wildcards select JSON. Defaults to JSON.
+func FormatFromAccept(accept string) Format {
+	best, bestQ := FormatJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		format, ok := formatFromMediaType(strings.ToLower(strings.TrimSpace(params[0])))
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// formatFromMediaType maps a media type from an Accept header to an output format
+func formatFromMediaType(mediaType string) (Format, bool) {
+	switch mediaType {
+	case "application/json", "application/*", "*/*":
+		return FormatJSON, true
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML, true
+	case "application/toml", "text/toml":
+		return FormatTOML, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the media type to send for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatYAML:
+		return "application/yaml"
+	case FormatTOML:
+		return "application/toml"
+	default:
+		return "application/json"
+	}
+}
+
+// EncodeConfig writes the configuration to w in the given format. Keys keep the names
+// used in the JSON output and are sorted in every format so outputs are diff-friendly.
+func EncodeConfig(w io.Writer, config HTTPProxyConfig, format Format) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(config)
+	case FormatYAML:
+		generic, err := toGeneric(config)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(generic); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatTOML:
+		generic, err := toGeneric(config)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := encodeTOMLTable(&buf, nil, generic); err != nil {
+			return err
+		}
+		_, err = w.Write(buf.Bytes())
+		return err
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// toGeneric converts the configuration into plain maps and slices via its JSON form,
+// so that other encoders see the same key names and omitted fields as JSON.
+func toGeneric(config HTTPProxyConfig) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// encodeTOMLTable writes a TOML table: plain key/values first, then sub-tables and
+// arrays of tables, each group in sorted key order.
+func encodeTOMLTable(buf *bytes.Buffer, path []string, table map[string]interface{}) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tables, arrayTables []string
+	var values []string
+	for _, key := range keys {
+		switch v := table[key].(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			tables = append(tables, key)
+		case []interface{}:
+			if isTableArray(v) {
+				arrayTables = append(arrayTables, key)
+			} else {
+				values = append(values, key)
+			}
+		default:
+			values = append(values, key)
+		}
+	}
+
+	if len(path) > 0 && (len(values) > 0 || len(tables)+len(arrayTables) == 0) {
+		fmt.Fprintf(buf, "[%s]\n", tomlPath(path))
+		for _, key := range values {
+			value, err := tomlValue(table[key])
+			if err != nil {
+				return fmt.Errorf("%s: %w", tomlPath(appendPath(path, key)), err)
+			}
+			fmt.Fprintf(buf, "%s = %s\n", tomlKey(key), value)
+		}
+		buf.WriteString("\n")
+	}
+
+	for _, key := range tables {
+		if err := encodeTOMLTable(buf, appendPath(path, key), table[key].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range arrayTables {
+		childPath := appendPath(path, key)
+		for _, item := range table[key].([]interface{}) {
+			fmt.Fprintf(buf, "[[%s]]\n", tomlPath(childPath))
+			if err := encodeTOMLArrayItem(buf, childPath, item.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// encodeTOMLArrayItem writes the body of an array-of-tables element whose [[header]]
+// has already been written.
+func encodeTOMLArrayItem(buf *bytes.Buffer, path []string, item map[string]interface{}) error {
+	scalars := make(map[string]interface{})
+	nested := make(map[string]interface{})
+	for key, value := range item {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			nested[key] = v
+		case []interface{}:
+			if isTableArray(v) {
+				nested[key] = v
+			} else {
+				scalars[key] = v
+			}
+		default:
+			scalars[key] = v
+		}
+	}
+
+	keys := make([]string, 0, len(scalars))
+	for key := range scalars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if scalars[key] == nil {
+			continue
+		}
+		value, err := tomlValue(scalars[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", tomlPath(appendPath(path, key)), err)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(key), value)
+	}
+	buf.WriteString("\n")
+
+	if len(nested) == 0 {
+		return nil
+	}
+	// Without values of its own, the nested tables are emitted relative to this element
+	return encodeTOMLTable(buf, path, nested)
+}
+
+// isTableArray reports whether a non-empty array consists only of tables
+func isTableArray(values []interface{}) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, value := range values {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlValue renders a scalar or inline array as a TOML value
+func tomlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			rendered, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, rendered)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, key := range keys {
+			rendered, err := tomlValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(key)+" = "+rendered)
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported TOML value of type %T", value)
+	}
+}
+
+// tomlString renders s as a TOML basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlKey renders a key bare when possible and quoted otherwise
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+// tomlPath renders a dotted table path
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// appendPath returns a new path with key appended, leaving path untouched
+func appendPath(path []string, key string) []string {
+	child := make([]string, len(path), len(path)+1)
+	copy(child, path)
+	return append(child, key)
+}
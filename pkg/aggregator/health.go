@@ -0,0 +1,71 @@
+package aggregator
+
+import (
+	"fmt"
+	"time"
+)
+
+// livenessIntervals is how many poll intervals may pass without a completed
+// aggregation before the poll loop is considered stuck
+const livenessIntervals = 3
+
+// recordAggregation notes the completion of an aggregation and how many of its
+// downstreams were fetched successfully
+func (a *Aggregator) recordAggregation(config *Config, results []downstreamResult) {
+	healthy := 0
+	for _, result := range results {
+		if result.err == nil {
+			healthy++
+		}
+	}
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+	a.lastAggregation = time.Now()
+	a.healthyDownstreams = healthy
+	a.totalDownstreams = len(config.Downstream)
+}
+
+// Ready returns nil once an aggregation has completed and, if health.min_healthy_percent
+// is set, enough downstreams were fetched successfully in the latest one. Otherwise the
+// error says why the aggregated configuration should not be served yet.
+func (a *Aggregator) Ready() error {
+	config, _ := a.currentSettings()
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	if a.lastAggregation.IsZero() {
+		return fmt.Errorf("waiting for the first aggregation")
+	}
+
+	minPercent := 0
+	if config.Health != nil {
+		minPercent = config.Health.MinHealthyPercent
+	}
+	if a.totalDownstreams > 0 && a.healthyDownstreams*100 < minPercent*a.totalDownstreams {
+		return fmt.Errorf("%d of %d downstreams healthy, %d%% required",
+			a.healthyDownstreams, a.totalDownstreams, minPercent)
+	}
+	return nil
+}
+
+// Live returns an error if no aggregation has completed within three poll intervals of
+// the most frequently polled downstream, counted from the last aggregation or, before
+// the first one, from NewAggregator.
+func (a *Aggregator) Live() error {
+	config, _ := a.currentSettings()
+	threshold := livenessIntervals * config.shortestPollInterval()
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	since := a.lastAggregation
+	if since.IsZero() {
+		since = a.createdAt
+	}
+	if elapsed := time.Since(since); elapsed > threshold {
+		return fmt.Errorf("no aggregation completed for %s", elapsed.Round(time.Second))
+	}
+	return nil
+}
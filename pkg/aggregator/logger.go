@@ -0,0 +1,57 @@
+package aggregator
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ParseLogLevel converts a log_level setting into a slog level.
+// An empty level defaults to warn.
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "", "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelWarn, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+	}
+}
+
+// NewLogger creates a leveled structured logger writing to w. Format is "text"
+// (the default) or "json".
+func NewLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	parsedLevel, err := ParseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedFormat, err := parseLogFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: parsedLevel}
+	if parsedFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// parseLogFormat normalises a log_format setting to "text" or "json"
+func parseLogFormat(format string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(format)); normalized {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return normalized, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (expected text or json)", format)
+	}
+}
@@ -0,0 +1,299 @@
+package aggregator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsNamespace prefixes all exported metric names
+const metricsNamespace = "traefik_config_middleware"
+
+// defaultDurationBuckets are the histogram buckets (in seconds) for fetch and
+// aggregation durations
+var defaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics collects aggregation metrics and exposes them in the Prometheus text
+// exposition format. It is safe for concurrent use.
+type Metrics struct {
+	mu sync.Mutex
+
+	fetchTotal          map[labelKey]float64 // downstream, result
+	fetchDuration       map[string]*histogram
+	lastSuccess         map[string]float64
+	routers             map[labelKey]float64 // downstream, protocol
+	services            map[labelKey]float64 // downstream, protocol
+	middlewares         map[string]float64
+	skippedRouters      map[labelKey]float64 // downstream, reason
+	aggregationDuration *histogram
+	configRequests      map[labelKey]float64 // format, code
+}
+
+// labelKey holds the values of a two-label metric series
+type labelKey [2]string
+
+// histogram is a cumulative Prometheus histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		fetchTotal:          make(map[labelKey]float64),
+		fetchDuration:       make(map[string]*histogram),
+		lastSuccess:         make(map[string]float64),
+		routers:             make(map[labelKey]float64),
+		services:            make(map[labelKey]float64),
+		middlewares:         make(map[string]float64),
+		skippedRouters:      make(map[labelKey]float64),
+		aggregationDuration: newHistogram(defaultDurationBuckets),
+		configRequests:      make(map[labelKey]float64),
+	}
+}
+
+// observeFetch records the outcome of fetching a single downstream
+func (m *Metrics) observeFetch(downstream string, result downstreamResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nothing was fetched, so there is no duration to record
+	if errors.Is(result.err, ErrCircuitOpen) {
+		m.fetchTotal[labelKey{downstream, "circuit_open"}]++
+		return
+	}
+
+	h, ok := m.fetchDuration[downstream]
+	if !ok {
+		h = newHistogram(defaultDurationBuckets)
+		m.fetchDuration[downstream] = h
+	}
+	h.observe(result.duration.Seconds())
+
+	if result.err != nil {
+		m.fetchTotal[labelKey{downstream, "failure"}]++
+		return
+	}
+	m.fetchTotal[labelKey{downstream, "success"}]++
+	m.lastSuccess[downstream] = float64(time.Now().UnixNano()) / 1e9
+
+	config := result.config
+	m.routers[labelKey{downstream, "http"}] = float64(len(config.HTTP.Routers))
+	m.routers[labelKey{downstream, "tcp"}] = float64(len(config.TCP.Routers))
+	m.routers[labelKey{downstream, "udp"}] = float64(len(config.UDP.Routers))
+	m.services[labelKey{downstream, "http"}] = float64(len(config.HTTP.Services))
+	m.services[labelKey{downstream, "tcp"}] = float64(len(config.TCP.Services))
+	m.services[labelKey{downstream, "udp"}] = float64(len(config.UDP.Services))
+	m.middlewares[downstream] = float64(len(config.HTTP.Middlewares))
+
+	// Reset previous reasons so counts reflect the latest fetch only
+	for key := range m.skippedRouters {
+		if key[0] == downstream {
+			m.skippedRouters[key] = 0
+		}
+	}
+	if result.stats != nil {
+		for reason, count := range result.stats.skipped {
+			m.skippedRouters[labelKey{downstream, reason}] = float64(count)
+		}
+	}
+}
+
+// retainDownstreams drops the series of downstreams not in present, so removed
+// downstreams disappear from the exposition after a configuration reload
+func (m *Metrics) retainDownstreams(present map[string]bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, series := range []map[labelKey]float64{m.fetchTotal, m.routers, m.services, m.skippedRouters} {
+		for key := range series {
+			if !present[key[0]] {
+				delete(series, key)
+			}
+		}
+	}
+	for downstream := range m.fetchDuration {
+		if !present[downstream] {
+			delete(m.fetchDuration, downstream)
+		}
+	}
+	for _, series := range []map[string]float64{m.lastSuccess, m.middlewares} {
+		for downstream := range series {
+			if !present[downstream] {
+				delete(series, downstream)
+			}
+		}
+	}
+}
+
+// observeAggregation records the duration of a complete aggregation cycle
+func (m *Metrics) observeAggregation(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.aggregationDuration.observe(duration.Seconds())
+}
+
+// ObserveConfigRequest counts a request to the config endpoint by format and status code
+func (m *Metrics) ObserveConfigRequest(format Format, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configRequests[labelKey{string(format), strconv.Itoa(code)}]++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Write writes all metrics in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	writeLabeled(bw, "downstream_fetch_total", "counter",
+		"Downstream fetch attempts by result.", m.fetchTotal, "downstream", "result")
+
+	writeFamily(bw, "downstream_fetch_duration_seconds", "histogram", "Duration of downstream fetches.")
+	for _, downstream := range sortedKeys(m.fetchDuration) {
+		writeHistogram(bw, "downstream_fetch_duration_seconds", [][2]string{{"downstream", downstream}}, m.fetchDuration[downstream])
+	}
+
+	writeFamily(bw, "downstream_last_success_timestamp_seconds", "gauge", "Unix time of the last successful fetch per downstream.")
+	for _, downstream := range sortedKeys(m.lastSuccess) {
+		writeSample(bw, "downstream_last_success_timestamp_seconds", [][2]string{{"downstream", downstream}}, m.lastSuccess[downstream])
+	}
+
+	writeLabeled(bw, "downstream_routers", "gauge",
+		"Routers produced per downstream in the last successful fetch.", m.routers, "downstream", "protocol")
+	writeLabeled(bw, "downstream_services", "gauge",
+		"Services produced per downstream in the last successful fetch.", m.services, "downstream", "protocol")
+
+	writeFamily(bw, "downstream_middlewares", "gauge", "Middlewares produced per downstream in the last successful fetch.")
+	for _, downstream := range sortedKeys(m.middlewares) {
+		writeSample(bw, "downstream_middlewares", [][2]string{{"downstream", downstream}}, m.middlewares[downstream])
+	}
+
+	writeLabeled(bw, "downstream_skipped_routers", "gauge",
+		"Downstream routers not promoted in the last successful fetch, by reason.", m.skippedRouters, "downstream", "reason")
+
+	writeFamily(bw, "aggregation_duration_seconds", "histogram", "Duration of complete aggregation cycles.")
+	writeHistogram(bw, "aggregation_duration_seconds", nil, m.aggregationDuration)
+
+	writeLabeled(bw, "config_requests_total", "counter",
+		"Requests to the config endpoint by format and status code.", m.configRequests, "format", "code")
+
+	return bw.Flush()
+}
+
+// writeFamily writes the HELP and TYPE lines of a metric family
+func writeFamily(w *bufio.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", metricsNamespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s %s\n", metricsNamespace, name, metricType)
+}
+
+// writeLabeled writes a metric family whose series are keyed by two labels
+func writeLabeled(w *bufio.Writer, name, metricType, help string, series map[labelKey]float64, label1, label2 string) {
+	writeFamily(w, name, metricType, help)
+
+	keys := make([]labelKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	for _, key := range keys {
+		writeSample(w, name, [][2]string{{label1, key[0]}, {label2, key[1]}}, series[key])
+	}
+}
+
+// writeHistogram writes the bucket, sum and count series of a histogram
+func writeHistogram(w *bufio.Writer, name string, labels [][2]string, h *histogram) {
+	for i, bound := range h.buckets {
+		bucketLabels := append(append([][2]string{}, labels...), [2]string{"le", formatFloat(bound)})
+		writeSample(w, name+"_bucket", bucketLabels, float64(h.counts[i]))
+	}
+	infLabels := append(append([][2]string{}, labels...), [2]string{"le", "+Inf"})
+	writeSample(w, name+"_bucket", infLabels, float64(h.count))
+	writeSample(w, name+"_sum", labels, h.sum)
+	writeSample(w, name+"_count", labels, float64(h.count))
+}
+
+// writeSample writes a single sample line
+func writeSample(w *bufio.Writer, name string, labels [][2]string, value float64) {
+	w.WriteString(metricsNamespace + "_" + name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label[0], escapeLabelValue(label[1]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines in label values
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a sample value the way Prometheus expects
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// sortedKeys returns the keys of a string-keyed map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Values accepted by router_middlewares.unmapped
+const (
+	UnmappedMiddlewaresDrop = "drop"
+	UnmappedMiddlewaresKeep = "keep"
+)
+
+// UnmarshalJSON decodes a middleware as returned by the Traefik API, keeping only
+// the definition matching its type and dropping usage and error details.
+func (m *TraefikMiddleware) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	m.Name, _ = raw["name"].(string)
+	m.Type, _ = raw["type"].(string)
+	m.Status, _ = raw["status"].(string)
+	m.Config = nil
+
+	// The API reports the type in lower case (e.g. redirectscheme) while the
+	// definition keeps its camel-cased key (redirectScheme)
+	for key, value := range raw {
+		if m.Type != "" && strings.EqualFold(key, m.Type) {
+			m.Config = map[string]interface{}{key: value}
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes a middleware the way the Traefik API returns it
+func (m TraefikMiddleware) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]interface{}, len(m.Config)+3)
+	for key, value := range m.Config {
+		raw[key] = value
+	}
+	raw["name"] = m.Name
+	raw["type"] = m.Type
+	if m.Status != "" {
+		raw["status"] = m.Status
+	}
+	return json.Marshal(raw)
+}
+
+// ShouldReplicateMiddleware reports whether a downstream middleware passes the type
+// filters of replicate_middlewares. Types are compared case-insensitively, so
+// forwardAuth matches the forwardauth type reported by the API.
+func ShouldReplicateMiddleware(middleware TraefikMiddleware, replicate *ReplicateConfig) bool {
+	if replicate == nil || !replicate.Enabled {
+		return false
+	}
+	for _, excluded := range replicate.ExcludeTypes {
+		if strings.EqualFold(middleware.Type, excluded) {
+			return false
+		}
+	}
+	if len(replicate.Types) == 0 {
+		return true
+	}
+	for _, included := range replicate.Types {
+		if strings.EqualFold(middleware.Type, included) {
+			return true
+		}
+	}
+	return false
+}
+
+// addReplicatedMiddlewares adds the downstream middlewares selected by
+// replicate_middlewares under prefixed names and returns the mapping from their
+// downstream to their upstream names, used to rewrite router references
+func (a *Aggregator) addReplicatedMiddlewares(config *HTTPProxyConfig, ds DownstreamConfig, middlewares []TraefikMiddleware) map[string]string {
+	replicated := make(map[string]string)
+	for _, middleware := range middlewares {
+		reason := ""
+		switch {
+		case !ShouldReplicateMiddleware(middleware, ds.Replicate):
+			reason = "type_filtered"
+		case middleware.Status == "disabled":
+			reason = "disabled"
+		case strings.EqualFold(middleware.Type, "chain"):
+			// Chains reference other middlewares by their downstream names
+			reason = "chain"
+		case len(middleware.Config) == 0:
+			reason = "no_definition"
+		}
+		if reason != "" {
+			a.log().Debug("Skipping middleware", "downstream", ds.Name, "middleware", middleware.Name, "reason", reason)
+			continue
+		}
+
+		baseName := middleware.Name
+		if idx := strings.Index(baseName, "@"); idx != -1 {
+			baseName = baseName[:idx]
+		}
+		upstreamName := fmt.Sprintf("%s-%s", ds.Name, baseName)
+
+		config.HTTP.Middlewares[upstreamName] = middleware.Config
+		replicated[middleware.Name] = upstreamName
+
+		a.log().Debug("Replicated middleware", "downstream", ds.Name, "middleware", middleware.Name,
+			"name", upstreamName, "type", middleware.Type)
+	}
+	return replicated
+}
+
+// RouterMiddlewares returns the middlewares an upstream router should reference:
+// the downstream's configured middlewares followed by the router's own middleware
+// references. Each reference is translated through router_middlewares.map, or
+// otherwise to the name of its replicated definition in replicated. References
+// mapped to an empty name are dropped, as are any others unless
+// router_middlewares.unmapped is "keep". Duplicates are removed, keeping the
+// first occurrence.
+func RouterMiddlewares(ds DownstreamConfig, router TraefikRouter, replicated map[string]string) []string {
+	if (ds.RouterMiddlewares == nil && len(replicated) == 0) || len(router.Middlewares) == 0 {
+		return ds.Middlewares
+	}
+
+	middlewares := make([]string, 0, len(ds.Middlewares)+len(router.Middlewares))
+	seen := make(map[string]bool, cap(middlewares))
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			middlewares = append(middlewares, name)
+		}
+	}
+
+	mapping := ds.RouterMiddlewares
+	if mapping == nil {
+		mapping = &RouterMiddlewaresConfig{}
+	}
+
+	for _, name := range ds.Middlewares {
+		add(name)
+	}
+	for _, name := range router.Middlewares {
+		if upstream, ok := mapping.Map[name]; ok {
+			add(upstream)
+		} else if upstream, ok := replicated[name]; ok {
+			add(upstream)
+		} else if mapping.Unmapped == UnmappedMiddlewaresKeep {
+			add(name)
+		}
+	}
+
+	if len(middlewares) == 0 {
+		return nil
+	}
+	return middlewares
+}
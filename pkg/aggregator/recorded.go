@@ -0,0 +1,93 @@
+package aggregator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recordedConfigFile is the recording served for requests to the api_url itself,
+// i.e. the full configuration of a passthrough downstream
+const recordedConfigFile = "config"
+
+// RecordedTransport answers downstream API requests from recorded JSON files instead
+// of the network. A request to <api_url>/api/http/routers of downstream "a" is served
+// from <dir>/a/api/http/routers.json, and a request to the api_url itself (passthrough
+// downstreams) from <dir>/a/config.json. Query parameters are ignored, so each
+// recording is returned as a single page. Missing recordings answer 404.
+type RecordedTransport struct {
+	dir         string
+	downstreams []DownstreamConfig
+}
+
+// NewRecordedTransport creates a transport serving the recordings in dir for the given downstreams
+func NewRecordedTransport(dir string, downstreams []DownstreamConfig) *RecordedTransport {
+	return &RecordedTransport{dir: dir, downstreams: downstreams}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RecordedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, rel, ok := t.match(req.URL)
+	if !ok {
+		return nil, fmt.Errorf("no downstream configured for %s", req.URL.Redacted())
+	}
+	if rel == "" {
+		rel = recordedConfigFile
+	}
+
+	path := filepath.Join(t.dir, filepath.FromSlash(name), filepath.FromSlash(rel)+".json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return recordedResponse(req, http.StatusNotFound, []byte("no recording at "+path)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recordedResponse(req, http.StatusOK, data), nil
+}
+
+// match finds the downstream whose api_url is the longest prefix of u and returns its
+// name together with the request path relative to the api_url
+func (t *RecordedTransport) match(u *url.URL) (string, string, bool) {
+	var name, rel string
+	longest := -1
+	for _, ds := range t.downstreams {
+		apiURL, err := url.Parse(ds.APIURL)
+		if err != nil || apiURL.Scheme != u.Scheme || apiURL.Host != u.Host {
+			continue
+		}
+		prefix := strings.TrimSuffix(apiURL.Path, "/")
+		if u.Path != prefix && !strings.HasPrefix(u.Path, prefix+"/") {
+			continue
+		}
+		if len(prefix) > longest {
+			longest = len(prefix)
+			name = ds.Name
+			rel = strings.Trim(strings.TrimPrefix(u.Path, prefix), "/")
+		}
+	}
+	return name, rel, longest >= 0
+}
+
+func recordedResponse(req *http.Request, status int, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,47 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// WatchConfigFile polls the file at path every interval and sends on changes whenever
+// its content differs from the previous poll. Rewrites with identical content and
+// unreadable files (e.g. mid-replacement) are ignored. Notifications are dropped while
+// a previous one is still pending. Returns when ctx is cancelled.
+func WatchConfigFile(ctx context.Context, path string, interval time.Duration, changes chan<- struct{}) {
+	last := hashFile(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := hashFile(path)
+			if current == nil || bytes.Equal(current, last) {
+				continue
+			}
+			last = current
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// hashFile returns the sha256 of the file content, or nil if it cannot be read
+func hashFile(path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
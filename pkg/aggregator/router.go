@@ -1,5 +1,7 @@
 package aggregator
 
+import "math"
+
 // ShouldIgnoreRouter checks if a router should be ignored based on its entrypoints.
 // Returns true if any of the router's entrypoints are in the ignore list.
 func ShouldIgnoreRouter(router TraefikRouter, ignoreEntryPoints []string) bool {
@@ -18,3 +20,55 @@ func ShouldIgnoreRouter(router TraefikRouter, ignoreEntryPoints []string) bool {
 
 	return false
 }
+
+// RouterStatusIncluded reports whether a router's status is one of includeStatus.
+// Without a list, and for routers the API reports no status for, every router is
+// included.
+func RouterStatusIncluded(router TraefikRouter, includeStatus []string) bool {
+	if len(includeStatus) == 0 || router.Status == "" {
+		return true
+	}
+	for _, status := range includeStatus {
+		if router.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// routerSkipReason returns why a downstream router should not be promoted upstream,
+// or an empty string if it should be
+func routerSkipReason(ds DownstreamConfig, router TraefikRouter) string {
+	switch {
+	case ShouldIgnoreRouter(router, ds.IgnoreEntryPoints):
+		return skipReasonIgnoredEntryPoint
+	case !RouterStatusIncluded(router, ds.IncludeStatus):
+		return skipReasonStatusPrefix + router.Status
+	default:
+		return ""
+	}
+}
+
+// RouterPriority returns the priority an upstream router should get: the downstream
+// router's priority plus the downstream's priority_offset. A router without an
+// explicit priority is ranked by rule length, as Traefik does, before the offset is
+// applied. Without either, 0 is returned so upstream Traefik uses its default. The
+// result is kept at 1 or above, since 0 would reset the router to the default.
+func RouterPriority(ds DownstreamConfig, router TraefikRouter) int {
+	priority := router.Priority
+	if ds.PriorityOffset == 0 {
+		return priority
+	}
+	if priority == 0 {
+		priority = len(router.Rule)
+	}
+
+	switch {
+	case ds.PriorityOffset > 0 && priority > math.MaxInt-ds.PriorityOffset:
+		return math.MaxInt
+	case priority+ds.PriorityOffset < 1:
+		return 1
+	default:
+		return priority + ds.PriorityOffset
+	}
+}
@@ -0,0 +1,114 @@
+package aggregator
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultListenAddr is the address served on when listen is not set
+const defaultListenAddr = ":8080"
+
+// ListenAddr returns the address the HTTP server listens on. Defaults to ":8080".
+func (c *Config) ListenAddr() string {
+	if c.Listen == "" {
+		return defaultListenAddr
+	}
+	return c.Listen
+}
+
+// BuildServerTLSConfig loads the server certificate and, if configured, the client CA
+// used to verify client certificates. Certificates are only verified when presented,
+// so health probes can connect without one; RequireClientCert enforces them on the
+// endpoints that need them. Returns nil when cfg is nil.
+func BuildServerTLSConfig(cfg *ServerTLSConfig) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("loading client CA: no certificates found in %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// RequireClientCert wraps next so that requests must present a client certificate
+// verified against client_ca_file. Requests without one get 403. Returns next
+// unchanged when no client CA is configured.
+func RequireClientCert(cfg *ServerTLSConfig, next http.Handler) http.Handler {
+	if cfg == nil || cfg.ClientCAFile == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAuth wraps next so that requests must carry the configured bearer token or
+// basic auth credentials. Unauthenticated requests get 401. Returns next unchanged
+// when auth is nil.
+func RequireAuth(auth *AuthConfig, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if auth.BearerToken != "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="traefik-config"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Basic realm="traefik-config", charset="UTF-8"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// authorized compares the request credentials in constant time
+func (auth *AuthConfig) authorized(r *http.Request) bool {
+	if auth.BearerToken != "" {
+		header := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(token), []byte(auth.BearerToken)) == 1
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username))
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password))
+	return usernameMatch&passwordMatch == 1
+}
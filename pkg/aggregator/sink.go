@@ -0,0 +1,119 @@
+package aggregator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileSink writes the aggregated configuration to a file for Traefik's file provider.
+// Files are replaced atomically and only rewritten when their content changes, so a
+// watching Traefik does not reload needlessly.
+type FileSink struct {
+	path   string
+	format Format
+
+	mu          sync.Mutex
+	lastWritten []byte
+}
+
+// NewFileSink creates a FileSink writing to path in the given format. If format is
+// empty it is inferred from the file extension (.json, .toml), defaulting to YAML.
+func NewFileSink(path string, format string) (*FileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("output file path is empty")
+	}
+
+	var parsed Format
+	if format == "" {
+		parsed = formatFromExtension(path)
+	} else {
+		var err error
+		parsed, err = ParseFormat(format)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileSink{path: path, format: parsed}, nil
+}
+
+// Path returns the file the sink writes to
+func (s *FileSink) Path() string {
+	return s.path
+}
+
+// Write encodes config and replaces the target file if the result differs from what
+// is already on disk. Returns true if the file was written.
+func (s *FileSink) Write(config HTTPProxyConfig) (bool, error) {
+	var buf bytes.Buffer
+	if err := EncodeConfig(&buf, config, s.format); err != nil {
+		return false, fmt.Errorf("encoding config: %w", err)
+	}
+	data := buf.Bytes()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// On the first write, compare against whatever a previous run left behind
+	if s.lastWritten == nil {
+		if existing, err := os.ReadFile(s.path); err == nil {
+			s.lastWritten = existing
+		}
+	}
+	if s.lastWritten != nil && bytes.Equal(s.lastWritten, data) {
+		return false, nil
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return false, err
+	}
+	s.lastWritten = data
+	return true, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into
+// place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return fmt.Errorf("setting file mode: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+// formatFromExtension infers the output format from a file name
+func formatFromExtension(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatYAML
+	}
+}
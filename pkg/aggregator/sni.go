@@ -0,0 +1,49 @@
+package aggregator
+
+import (
+	"fmt"
+)
+
+// addSNIPassthroughRouter converts a TLS-enabled downstream HTTP router into a TCP router
+// matching its hostnames with HostSNI and passing TLS through to the downstream untouched.
+// Routers sharing the same hostnames are collapsed into a single TCP router. Returns false
+// if no hostname could be extracted from the rule, in which case the caller should fall
+// back to an HTTP router.
+func (a *Aggregator) addSNIPassthroughRouter(config *HTTPProxyConfig, ds DownstreamConfig, router TraefikRouter, routerBaseName string, entryPoints []string) bool {
+	domains := ExtractDomainsFromRule(router.Rule, true)
+	if len(domains) == 0 {
+		a.log().Debug("No hostname in rule of TLS router, keeping HTTP route", "downstream", ds.Name, "router", router.Name)
+		return false
+	}
+
+	rule := BuildHostSNIRule(domains)
+	for _, existing := range config.TCP.Routers {
+		if existing.Rule == rule {
+			a.log().Debug("Skipping TCP passthrough, hostnames already routed", "downstream", ds.Name, "router", router.Name)
+			return true
+		}
+	}
+
+	tcpRouterName := fmt.Sprintf("%s-%s", ds.Name, routerBaseName)
+	tcpServiceName := fmt.Sprintf("service-%s-%s", ds.Name, routerBaseName)
+	backendAddress := GetSNIPassthroughAddress(ds)
+
+	// The downstream priority belongs to the HTTP rule, so only the offset carries over
+	config.TCP.Routers[tcpRouterName] = TCPRouter{
+		Rule:        rule,
+		Service:     tcpServiceName,
+		EntryPoints: entryPoints,
+		Priority:    RouterPriority(ds, TraefikRouter{Rule: rule}),
+		TLS:         map[string]interface{}{"passthrough": true},
+	}
+
+	config.TCP.Services[tcpServiceName] = TCPService{
+		LoadBalancer: TCPLoadBalancer{
+			Servers: []TCPServer{{Address: backendAddress}},
+		},
+	}
+
+	a.log().Debug("Added TCP passthrough route", "downstream", ds.Name, "router", router.Name,
+		"rule", rule, "backend", backendAddress)
+	return true
+}
@@ -0,0 +1,218 @@
+package aggregator
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// downstreamState is what the aggregator knows about a downstream from its recent fetches.
+// Router counts are those of the last successful fetch.
+type downstreamState struct {
+	lastAttempt time.Time
+	lastSuccess time.Time
+	lastErr     error
+	duration    time.Duration
+	attempts    int
+	fetched     int
+	skipped     map[string]int
+	skippedList []SkippedRouter
+	emitted     int
+	current     HTTPProxyConfig // what the downstream contributes to the aggregated config
+	nextPoll    time.Time
+
+	consecutiveFailures int
+	openUntil           time.Time // zero while the circuit breaker is closed
+}
+
+// Status is the state of the aggregator as served on /status
+type Status struct {
+	LastAggregation *time.Time         `json:"lastAggregation,omitempty"`
+	Downstreams     []DownstreamStatus `json:"downstreams"`
+}
+
+// DownstreamStatus reports the outcome of the recent fetches of a single downstream.
+// RoutersFetched and RoutersSkipped describe the last successful fetch, RoutersEmitted
+// what the downstream currently contributes to the aggregated configuration.
+type DownstreamStatus struct {
+	Name                string          `json:"name"`
+	APIURL              string          `json:"apiUrl"`
+	Passthrough         bool            `json:"passthrough,omitempty"`
+	LastAttempt         *time.Time      `json:"lastAttempt,omitempty"`
+	LastSuccess         *time.Time      `json:"lastSuccess,omitempty"`
+	LastError           string          `json:"lastError,omitempty"`
+	FetchLatencySeconds float64         `json:"fetchLatencySeconds"`
+	Attempts            int             `json:"attempts,omitempty"`
+	ConsecutiveFailures int             `json:"consecutiveFailures"`
+	Circuit             string          `json:"circuit"`
+	CircuitOpenUntil    *time.Time      `json:"circuitOpenUntil,omitempty"`
+	PollIntervalSeconds float64         `json:"pollIntervalSeconds"`
+	NextPoll            *time.Time      `json:"nextPoll,omitempty"`
+	RoutersFetched      int             `json:"routersFetched"`
+	RoutersSkipped      int             `json:"routersSkipped"`
+	SkippedByReason     map[string]int  `json:"skippedByReason,omitempty"`
+	SkippedRouters      []SkippedRouter `json:"skippedRouters,omitempty"`
+	RoutersEmitted      int             `json:"routersEmitted"`
+	Stale               bool            `json:"stale"`
+}
+
+// SkippedRouter is a downstream router that was not promoted upstream, with the
+// errors the downstream reported for it
+type SkippedRouter struct {
+	Name   string   `json:"name"`
+	Reason string   `json:"reason"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// recordFetch stores the outcome of fetching a downstream together with what it now
+// contributes to the aggregated configuration and when it is due again.
+// A downstream skipped by its open circuit breaker keeps its previous fetch details.
+func (a *Aggregator) recordFetch(ds DownstreamConfig, result downstreamResult, contribution HTTPProxyConfig, nextPoll time.Time) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	state, ok := a.downstream[ds.Name]
+	if !ok {
+		state = &downstreamState{}
+		a.downstream[ds.Name] = state
+	}
+
+	state.current = contribution
+	state.emitted = countRouters(contribution)
+	state.nextPoll = nextPoll
+	a.updateCircuit(ds, state, result.err)
+	if errors.Is(result.err, ErrCircuitOpen) {
+		return
+	}
+
+	state.lastAttempt = result.startedAt
+	state.duration = result.duration
+	state.attempts = result.attempts
+	state.lastErr = result.err
+	if result.err != nil {
+		return
+	}
+
+	state.lastSuccess = result.startedAt
+	if result.stats != nil {
+		state.fetched = result.stats.fetched
+		state.skipped = result.stats.skipped
+		state.skippedList = result.stats.skippedRouters
+	}
+}
+
+// dueDownstreams reports which downstreams to fetch in an aggregation starting at now.
+// Without dueOnly all of them are.
+func (a *Aggregator) dueDownstreams(config *Config, now time.Time, dueOnly bool) []bool {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	due := make([]bool, len(config.Downstream))
+	for i, ds := range config.Downstream {
+		state, ok := a.downstream[ds.Name]
+		due[i] = !dueOnly || !ok || !now.Before(state.nextPoll)
+	}
+	return due
+}
+
+// previousResult returns the outcome of the last fetch of a downstream that is not due,
+// carrying the configuration it currently contributes. A stale configuration whose
+// stale_ttl has expired since is dropped.
+func (a *Aggregator) previousResult(ds DownstreamConfig) downstreamResult {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	state, ok := a.downstream[ds.Name]
+	if !ok {
+		return downstreamResult{config: newHTTPProxyConfig()}
+	}
+	if a.stale[ds.Name] {
+		if snapshot, ok := a.lastGood[ds.Name]; !ok || time.Since(snapshot.fetchedAt) > ds.StaleTTLDuration() {
+			delete(a.lastGood, ds.Name)
+			delete(a.stale, ds.Name)
+			state.current = newHTTPProxyConfig()
+			state.emitted = 0
+		}
+	}
+	return downstreamResult{config: state.current, err: state.lastErr}
+}
+
+// Status returns the state of every configured downstream, in configuration order
+func (a *Aggregator) Status() Status {
+	config, _ := a.currentSettings()
+
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
+	status := Status{Downstreams: make([]DownstreamStatus, 0, len(config.Downstream))}
+	if !a.lastAggregation.IsZero() {
+		status.LastAggregation = timePtr(a.lastAggregation)
+	}
+
+	for _, ds := range config.Downstream {
+		entry := DownstreamStatus{
+			Name:        ds.Name,
+			APIURL:      redactURL(ds.APIURL),
+			Passthrough: ds.Passthrough,
+			Stale:       a.stale[ds.Name],
+
+			PollIntervalSeconds: config.DownstreamPollInterval(ds).Seconds(),
+		}
+
+		state, ok := a.downstream[ds.Name]
+		entry.Circuit = circuitState(state)
+		if ok {
+			if !state.openUntil.IsZero() {
+				entry.CircuitOpenUntil = timePtr(state.openUntil)
+			}
+			if !state.nextPoll.IsZero() {
+				entry.NextPoll = timePtr(state.nextPoll)
+			}
+			entry.ConsecutiveFailures = state.consecutiveFailures
+			entry.Attempts = state.attempts
+			if !state.lastAttempt.IsZero() {
+				entry.LastAttempt = timePtr(state.lastAttempt)
+			}
+			if !state.lastSuccess.IsZero() {
+				entry.LastSuccess = timePtr(state.lastSuccess)
+			}
+			if state.lastErr != nil {
+				entry.LastError = state.lastErr.Error()
+			}
+			entry.FetchLatencySeconds = state.duration.Seconds()
+			entry.RoutersFetched = state.fetched
+			entry.RoutersEmitted = state.emitted
+			if len(state.skipped) > 0 {
+				entry.SkippedByReason = make(map[string]int, len(state.skipped))
+				for reason, count := range state.skipped {
+					entry.SkippedByReason[reason] = count
+					entry.RoutersSkipped += count
+				}
+			}
+			entry.SkippedRouters = append([]SkippedRouter(nil), state.skippedList...)
+		}
+
+		status.Downstreams = append(status.Downstreams, entry)
+	}
+
+	return status
+}
+
+// countRouters returns the number of HTTP, TCP and UDP routers in a configuration
+func countRouters(config HTTPProxyConfig) int {
+	return len(config.HTTP.Routers) + len(config.TCP.Routers) + len(config.UDP.Routers)
+}
+
+// redactURL hides any password embedded in a URL
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Redacted()
+}
+
+func timePtr(t time.Time) *time.Time {
+	t = t.UTC()
+	return &t
+}
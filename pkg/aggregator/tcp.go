@@ -0,0 +1,65 @@
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// addTCPRouters generates an upstream TCP router and service for each downstream
+// TCP router, pointing back at the downstream Traefik. Routers on ignored entrypoints,
+// with an excluded status or without a reachable backend are skipped and the
+// downstream's entrypoint override is applied, as for HTTP.
+func (a *Aggregator) addTCPRouters(config *HTTPProxyConfig, ds DownstreamConfig, routers []TraefikRouter, stats *downstreamStats) {
+	for _, router := range routers {
+		useTLS := len(router.TLS) > 0
+		passthrough, _ := router.TLS["passthrough"].(bool)
+
+		reason := routerSkipReason(ds, router)
+		backendAddress, ok := GetTCPRouterBackendAddress(ds, router.EntryPoints, useTLS)
+		if reason == "" && !ok {
+			reason = skipReasonNoBackend
+		}
+		if reason != "" {
+			a.log().Debug("Skipping TCP router", "downstream", ds.Name, "router", router.Name, "reason", reason, "errors", router.Error)
+			stats.skip(router, reason)
+			continue
+		}
+
+		routerBaseName := router.Name
+		if idx := strings.Index(routerBaseName, "@"); idx != -1 {
+			routerBaseName = routerBaseName[:idx]
+		}
+
+		tcpRouterName := fmt.Sprintf("%s-%s", ds.Name, routerBaseName)
+		tcpServiceName := fmt.Sprintf("service-%s-%s", ds.Name, routerBaseName)
+
+		entryPoints := router.EntryPoints
+		if len(ds.EntryPoints) > 0 {
+			entryPoints = ds.EntryPoints
+		}
+
+		tcpRouter := TCPRouter{
+			Rule:        router.Rule,
+			Service:     tcpServiceName,
+			EntryPoints: entryPoints,
+			Priority:    RouterPriority(ds, router),
+		}
+
+		if useTLS {
+			tcpRouter.TLS = BuildTCPTLSConfig(ds, router.Rule, router.TLS)
+		}
+
+		config.TCP.Routers[tcpRouterName] = tcpRouter
+
+		// When the upstream terminates TLS, re-encrypt towards the downstream
+		// so its HostSNI router still sees a TLS connection
+		config.TCP.Services[tcpServiceName] = TCPService{
+			LoadBalancer: TCPLoadBalancer{
+				Servers: []TCPServer{{Address: backendAddress, TLS: useTLS && !passthrough}},
+			},
+		}
+
+		a.log().Debug("Added TCP route", "downstream", ds.Name, "router", router.Name,
+			"rule", router.Rule, "backend", backendAddress, "tls", useTLS, "passthrough", passthrough)
+	}
+}
@@ -36,3 +36,41 @@ func BuildTLSConfig(ds DownstreamConfig, rule string, existingTLS map[string]int
 
 	return tlsConfig
 }
+
+// BuildTCPTLSConfig constructs a TLS configuration map for a TCP router.
+// Passthrough routers keep their TLS options untouched since the upstream never
+// terminates TLS for them. Otherwise certResolver handling and domain extraction
+// follow BuildTLSConfig, using HostSNI() patterns from the rule.
+func BuildTCPTLSConfig(ds DownstreamConfig, rule string, existingTLS map[string]interface{}) map[string]interface{} {
+	tlsConfig := make(map[string]interface{})
+
+	for k, v := range existingTLS {
+		if k != "domains" { // We'll rebuild domains
+			tlsConfig[k] = v
+		}
+	}
+
+	if passthrough, _ := tlsConfig["passthrough"].(bool); passthrough {
+		delete(tlsConfig, "certResolver")
+		return tlsConfig
+	}
+
+	if ds.TLS != nil && ds.TLS.CertResolver != "" {
+		tlsConfig["certResolver"] = ds.TLS.CertResolver
+	}
+
+	if ds.TLS != nil && ds.TLS.StripResolver {
+		delete(tlsConfig, "certResolver")
+	}
+
+	domains := ExtractSNIDomainsFromRule(rule, ds.WildcardFix)
+	if len(domains) > 0 {
+		tlsDomain := TLSDomain{Main: domains[0]}
+		if len(domains) > 1 {
+			tlsDomain.Sans = domains[1:]
+		}
+		tlsConfig["domains"] = []TLSDomain{tlsDomain}
+	}
+
+	return tlsConfig
+}
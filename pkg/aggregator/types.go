@@ -2,16 +2,109 @@ package aggregator
 
 // Config represents the application configuration
 type Config struct {
-	Downstream   []DownstreamConfig `yaml:"downstream"`
-	PollInterval string             `yaml:"poll_interval"`
-	HTTPTimeout  string             `yaml:"http_timeout"`
-	LogLevel     string             `yaml:"log_level"`
+	Downstream     []DownstreamConfig `yaml:"downstream"`
+	PollInterval   string             `yaml:"poll_interval"`
+	HTTPTimeout    string             `yaml:"http_timeout"`
+	LogLevel       string             `yaml:"log_level"`
+	LogFormat      string             `yaml:"log_format"`
+	MaxConcurrency int                `yaml:"max_concurrency"`
+	OutputFile     *OutputFileConfig  `yaml:"output_file"`
+	Listen         string             `yaml:"listen"`
+	ServerTLS      *ServerTLSConfig   `yaml:"server_tls"`
+	Auth           *AuthConfig        `yaml:"auth"`
+	Health         *HealthConfig      `yaml:"health"`
+	// Middlewares are emitted unchanged into the aggregated configuration, keyed by
+	// name, so downstreams can reference them by that name in their middlewares
+	Middlewares map[string]map[string]interface{} `yaml:"middlewares"`
+}
+
+// HealthConfig tunes the readiness check
+type HealthConfig struct {
+	// MinHealthyPercent is the share of downstreams whose latest fetch must have
+	// succeeded for the aggregator to report ready
+	MinHealthyPercent int `yaml:"min_healthy_percent"`
+}
+
+// ServerTLSConfig enables HTTPS on the listener. With ClientCAFile set, clients must
+// present a certificate signed by that CA (mTLS).
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// AuthConfig requires requests to the config endpoint to authenticate with either a
+// bearer token or basic auth credentials
+type AuthConfig struct {
+	BearerToken string `yaml:"bearer_token"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+}
+
+// OutputFileConfig configures writing the aggregated configuration to disk
+type OutputFileConfig struct {
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"`
 }
 
 // TLSConfig holds TLS-specific configuration for a downstream
 type TLSConfig struct {
 	CertResolver  string `yaml:"cert_resolver"`
 	StripResolver bool   `yaml:"strip_resolver"`
+	Passthrough   bool   `yaml:"passthrough"`
+}
+
+// TCPConfig holds settings for aggregating TCP routers from a downstream. Backends
+// maps a downstream entrypoint to the host:port (or just :port on the downstream
+// host) its routers are reached at.
+type TCPConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	BackendOverride string            `yaml:"backend_override"`
+	Backends        map[string]string `yaml:"backends"`
+}
+
+// UDPConfig holds settings for aggregating UDP routers from a downstream. Backends
+// maps a downstream entrypoint to the host:port (or just :port on the downstream
+// host) its routers are reached at; EntryPoints renames downstream entrypoints to
+// upstream ones.
+type UDPConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	BackendOverride string            `yaml:"backend_override"`
+	Backends        map[string]string `yaml:"backends"`
+	EntryPoints     map[string]string `yaml:"entrypoints"`
+}
+
+// RetryConfig retries a failed fetch within the same poll cycle, waiting an
+// exponentially growing, jittered backoff between attempts
+type RetryConfig struct {
+	Attempts       int    `yaml:"attempts"`
+	InitialBackoff string `yaml:"initial_backoff"`
+	MaxBackoff     string `yaml:"max_backoff"`
+}
+
+// CircuitBreakerConfig stops polling a downstream for a cool-down period after a
+// number of consecutive failed poll cycles
+type CircuitBreakerConfig struct {
+	FailureThreshold int    `yaml:"failure_threshold"`
+	Cooldown         string `yaml:"cooldown"`
+}
+
+// RouterMiddlewaresConfig carries the middleware references of downstream routers
+// upstream. Map translates a downstream name (e.g. auth@kubernetescrd) to the name of
+// an upstream middleware; an empty value drops the reference. Unmapped references
+// are dropped unless Unmapped is "keep".
+type RouterMiddlewaresConfig struct {
+	Map      map[string]string `yaml:"map"`
+	Unmapped string            `yaml:"unmapped"`
+}
+
+// ReplicateConfig copies the HTTP middleware definitions of a downstream into the
+// aggregated configuration. Types limits replication to the listed middleware types
+// and ExcludeTypes skips the listed ones (e.g. forwardAuth to a cluster-internal URL).
+type ReplicateConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Types        []string `yaml:"types"`
+	ExcludeTypes []string `yaml:"exclude_types"`
 }
 
 // TLSDomain represents a single domain entry for TLS certificates
@@ -22,26 +115,53 @@ type TLSDomain struct {
 
 // DownstreamConfig represents configuration for a single downstream Traefik instance
 type DownstreamConfig struct {
-	Name              string     `yaml:"name"`
-	APIURL            string     `yaml:"api_url"`
-	BackendOverride   string     `yaml:"backend_override"`
-	APIKey            string     `yaml:"api_key"`
-	TLS               *TLSConfig `yaml:"tls"`
-	EntryPoints       []string   `yaml:"entrypoints"`
-	Middlewares       []string   `yaml:"middlewares"`
-	IgnoreEntryPoints []string   `yaml:"ignore_entrypoints"`
-	WildcardFix       bool       `yaml:"wildcard_fix"`
-	Passthrough       bool       `yaml:"passthrough"`
-	ServerTransport   string     `yaml:"server_transport"`
-}
-
-// TraefikRouter represents a router from the Traefik API
+	Name              string                   `yaml:"name"`
+	APIURL            string                   `yaml:"api_url"`
+	BackendOverride   string                   `yaml:"backend_override"`
+	APIKey            string                   `yaml:"api_key"`
+	TLS               *TLSConfig               `yaml:"tls"`
+	EntryPoints       []string                 `yaml:"entrypoints"`
+	Middlewares       []string                 `yaml:"middlewares"`
+	IgnoreEntryPoints []string                 `yaml:"ignore_entrypoints"`
+	WildcardFix       bool                     `yaml:"wildcard_fix"`
+	Passthrough       bool                     `yaml:"passthrough"`
+	ServerTransport   string                   `yaml:"server_transport"`
+	StaleTTL          string                   `yaml:"stale_ttl"`
+	TCP               *TCPConfig               `yaml:"tcp"`
+	UDP               *UDPConfig               `yaml:"udp"`
+	Timeout           string                   `yaml:"timeout"`
+	PollInterval      string                   `yaml:"poll_interval"`
+	Retry             *RetryConfig             `yaml:"retry"`
+	CircuitBreaker    *CircuitBreakerConfig    `yaml:"circuit_breaker"`
+	RouterMiddlewares *RouterMiddlewaresConfig `yaml:"router_middlewares"`
+	Replicate         *ReplicateConfig         `yaml:"replicate_middlewares"`
+	PriorityOffset    int                      `yaml:"priority_offset"`
+	IncludeStatus     []string                 `yaml:"include_status"`
+}
+
+// TraefikRouter represents an HTTP, TCP or UDP router from the Traefik API.
+// UDP routers have no rule or TLS settings. Status is "enabled", "disabled" or
+// "warning", with the problems Traefik found listed in Error.
 type TraefikRouter struct {
 	Name        string                 `json:"name"`
 	EntryPoints []string               `json:"entryPoints"`
 	Service     string                 `json:"service"`
 	Rule        string                 `json:"rule"`
+	Priority    int                    `json:"priority,omitempty"`
+	Middlewares []string               `json:"middlewares,omitempty"`
 	TLS         map[string]interface{} `json:"tls,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Error       []string               `json:"error,omitempty"`
+}
+
+// TraefikMiddleware represents an HTTP middleware from the Traefik API. Config holds
+// the definition keyed by its type, as it appears in a dynamic configuration
+// (e.g. {"headers": {...}}).
+type TraefikMiddleware struct {
+	Name   string
+	Type   string
+	Status string
+	Config map[string]interface{}
 }
 
 // HTTPRouter represents an HTTP router in the output configuration
@@ -49,6 +169,7 @@ type HTTPRouter struct {
 	Rule        string                 `json:"rule"`
 	Service     string                 `json:"service"`
 	EntryPoints []string               `json:"entryPoints"`
+	Priority    int                    `json:"priority,omitempty"`
 	Middlewares []string               `json:"middlewares,omitempty"`
 	TLS         map[string]interface{} `json:"tls,omitempty"`
 }
@@ -76,7 +197,67 @@ type HTTPBlock struct {
 	Middlewares map[string]interface{} `json:"middlewares,omitempty"`
 }
 
+// TCPRouter represents a TCP router in the output configuration
+type TCPRouter struct {
+	Rule        string                 `json:"rule"`
+	Service     string                 `json:"service"`
+	EntryPoints []string               `json:"entryPoints"`
+	Priority    int                    `json:"priority,omitempty"`
+	TLS         map[string]interface{} `json:"tls,omitempty"`
+}
+
+// TCPServer represents a TCP backend server
+type TCPServer struct {
+	Address string `json:"address"`
+	TLS     bool   `json:"tls,omitempty"`
+}
+
+// TCPLoadBalancer represents TCP load balancer configuration
+type TCPLoadBalancer struct {
+	Servers []TCPServer `json:"servers"`
+}
+
+// TCPService represents a TCP service in the output configuration
+type TCPService struct {
+	LoadBalancer TCPLoadBalancer `json:"loadBalancer"`
+}
+
+// TCPBlock contains TCP routers and services
+type TCPBlock struct {
+	Routers  map[string]TCPRouter  `json:"routers"`
+	Services map[string]TCPService `json:"services"`
+}
+
+// UDPRouter represents a UDP router in the output configuration
+type UDPRouter struct {
+	Service     string   `json:"service"`
+	EntryPoints []string `json:"entryPoints"`
+}
+
+// UDPServer represents a UDP backend server
+type UDPServer struct {
+	Address string `json:"address"`
+}
+
+// UDPLoadBalancer represents UDP load balancer configuration
+type UDPLoadBalancer struct {
+	Servers []UDPServer `json:"servers"`
+}
+
+// UDPService represents a UDP service in the output configuration
+type UDPService struct {
+	LoadBalancer UDPLoadBalancer `json:"loadBalancer"`
+}
+
+// UDPBlock contains UDP routers and services
+type UDPBlock struct {
+	Routers  map[string]UDPRouter  `json:"routers"`
+	Services map[string]UDPService `json:"services"`
+}
+
 // HTTPProxyConfig is the complete output configuration
 type HTTPProxyConfig struct {
 	HTTP HTTPBlock `json:"http"`
+	TCP  TCPBlock  `json:"tcp"`
+	UDP  UDPBlock  `json:"udp"`
 }
@@ -0,0 +1,65 @@
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// addUDPRouters generates an upstream UDP router and service for each downstream
+// UDP router, pointing back at the downstream Traefik. Routers on ignored entrypoints
+// or with an excluded status are skipped, as are routers on entrypoints without a
+// UDP backend address. Entrypoints are renamed through the downstream's UDP
+// entrypoint mapping.
+func (a *Aggregator) addUDPRouters(config *HTTPProxyConfig, ds DownstreamConfig, routers []TraefikRouter, stats *downstreamStats) {
+	for _, router := range routers {
+		reason := routerSkipReason(ds, router)
+		backendAddress, ok := GetUDPBackendAddress(ds, router.EntryPoints)
+		if reason == "" && !ok {
+			reason = skipReasonNoBackend
+		}
+		if reason != "" {
+			a.log().Debug("Skipping UDP router", "downstream", ds.Name, "router", router.Name, "reason", reason, "errors", router.Error)
+			stats.skip(router, reason)
+			continue
+		}
+
+		routerBaseName := router.Name
+		if idx := strings.Index(routerBaseName, "@"); idx != -1 {
+			routerBaseName = routerBaseName[:idx]
+		}
+
+		udpRouterName := fmt.Sprintf("%s-%s", ds.Name, routerBaseName)
+		udpServiceName := fmt.Sprintf("service-%s-%s", ds.Name, routerBaseName)
+
+		config.UDP.Routers[udpRouterName] = UDPRouter{
+			Service:     udpServiceName,
+			EntryPoints: MapUDPEntryPoints(router.EntryPoints, ds.UDP),
+		}
+
+		config.UDP.Services[udpServiceName] = UDPService{
+			LoadBalancer: UDPLoadBalancer{
+				Servers: []UDPServer{{Address: backendAddress}},
+			},
+		}
+
+		a.log().Debug("Added UDP route", "downstream", ds.Name, "router", router.Name, "backend", backendAddress)
+	}
+}
+
+// MapUDPEntryPoints renames downstream UDP entrypoints to their upstream names.
+// Entrypoints without a mapping keep their downstream name.
+func MapUDPEntryPoints(entryPoints []string, udp *UDPConfig) []string {
+	if udp == nil || len(udp.EntryPoints) == 0 {
+		return entryPoints
+	}
+
+	mapped := make([]string, len(entryPoints))
+	for i, ep := range entryPoints {
+		if upstream, ok := udp.EntryPoints[ep]; ok {
+			mapped[i] = upstream
+		} else {
+			mapped[i] = ep
+		}
+	}
+	return mapped
+}
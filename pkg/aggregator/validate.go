@@ -0,0 +1,350 @@
+package aggregator
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem with the configuration. Field is the
+// path of the offending setting, e.g. "downstream[1].api_url". Line is the line in
+// the YAML file it was found at, or 0 when unknown.
+type ValidationError struct {
+	Field   string
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors lists every problem found in a configuration
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+// passthroughIgnoredOptions are downstream settings that have no effect when the
+// downstream's configuration is passed through unchanged
+var passthroughIgnoredOptions = []struct {
+	field string
+	isSet func(ds DownstreamConfig) bool
+}{
+	{"wildcard_fix", func(ds DownstreamConfig) bool { return ds.WildcardFix }},
+	{"backend_override", func(ds DownstreamConfig) bool { return ds.BackendOverride != "" }},
+	{"tls", func(ds DownstreamConfig) bool { return ds.TLS != nil }},
+	{"entrypoints", func(ds DownstreamConfig) bool { return len(ds.EntryPoints) > 0 }},
+	{"middlewares", func(ds DownstreamConfig) bool { return len(ds.Middlewares) > 0 }},
+	{"ignore_entrypoints", func(ds DownstreamConfig) bool { return len(ds.IgnoreEntryPoints) > 0 }},
+	{"server_transport", func(ds DownstreamConfig) bool { return ds.ServerTransport != "" }},
+	{"tcp", func(ds DownstreamConfig) bool { return ds.TCP != nil }},
+	{"udp", func(ds DownstreamConfig) bool { return ds.UDP != nil }},
+	{"router_middlewares", func(ds DownstreamConfig) bool { return ds.RouterMiddlewares != nil }},
+	{"replicate_middlewares", func(ds DownstreamConfig) bool { return ds.Replicate != nil }},
+	{"priority_offset", func(ds DownstreamConfig) bool { return ds.PriorityOffset != 0 }},
+	{"include_status", func(ds DownstreamConfig) bool { return len(ds.IncludeStatus) > 0 }},
+}
+
+// Validate checks the configuration for missing required fields, malformed URLs
+// and durations, duplicate downstream names and conflicting options. All problems
+// found are returned as ValidationErrors; Line is not set since a Config built in
+// code has no source file. LoadConfig reports the same problems with line numbers.
+func (c *Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Config) validate() ValidationErrors {
+	var errs ValidationErrors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	checkBackends := func(field string, backends map[string]string) {
+		for _, ep := range sortedKeys(backends) {
+			if _, port, err := net.SplitHostPort(backends[ep]); err != nil || port == "" {
+				add(field+"."+ep, "%q is not a host:port or :port address", backends[ep])
+			}
+		}
+	}
+	checkDuration := func(field, value string) {
+		if value == "" {
+			return
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			add(field, "invalid duration %q (e.g. 30s, 5m)", value)
+		} else if duration <= 0 {
+			add(field, "must be positive")
+		}
+	}
+
+	if len(c.Downstream) == 0 {
+		add("downstream", "at least one downstream is required")
+	}
+
+	names := make(map[string]int, len(c.Downstream))
+	for i, ds := range c.Downstream {
+		field := fmt.Sprintf("downstream[%d]", i)
+
+		if ds.Name == "" {
+			add(field+".name", "is required")
+		} else if first, ok := names[ds.Name]; ok {
+			add(field+".name", "duplicate name %q (also used by downstream[%d])", ds.Name, first)
+		} else {
+			names[ds.Name] = i
+		}
+
+		if ds.APIURL == "" {
+			add(field+".api_url", "is required")
+		} else if err := checkHTTPURL(ds.APIURL); err != nil {
+			add(field+".api_url", "%v", err)
+		}
+		if ds.BackendOverride != "" {
+			if err := checkBackendOverride(ds.BackendOverride); err != nil {
+				add(field+".backend_override", "%v", err)
+			}
+		}
+
+		checkDuration(field+".timeout", ds.Timeout)
+		checkDuration(field+".stale_ttl", ds.StaleTTL)
+		checkDuration(field+".poll_interval", ds.PollInterval)
+
+		if ds.Retry != nil {
+			if ds.Retry.Attempts < 0 {
+				add(field+".retry.attempts", "must not be negative")
+			}
+			checkDuration(field+".retry.initial_backoff", ds.Retry.InitialBackoff)
+			checkDuration(field+".retry.max_backoff", ds.Retry.MaxBackoff)
+		}
+		if ds.CircuitBreaker != nil {
+			if ds.CircuitBreaker.FailureThreshold < 0 {
+				add(field+".circuit_breaker.failure_threshold", "must not be negative")
+			}
+			checkDuration(field+".circuit_breaker.cooldown", ds.CircuitBreaker.Cooldown)
+		}
+
+		if ds.TCP != nil && ds.TCP.BackendOverride != "" {
+			if _, _, err := net.SplitHostPort(ds.TCP.BackendOverride); err != nil {
+				add(field+".tcp.backend_override", "%q is not a host:port address", ds.TCP.BackendOverride)
+			}
+		}
+		if ds.TCP != nil {
+			checkBackends(field+".tcp.backends", ds.TCP.Backends)
+		}
+		if ds.UDP != nil {
+			checkBackends(field+".udp.backends", ds.UDP.Backends)
+			if ds.UDP.Enabled && ds.UDP.BackendOverride == "" && len(ds.UDP.Backends) == 0 {
+				add(field+".udp", "requires backends or backend_override, since the downstream's HTTP port does not serve UDP")
+			}
+		}
+		if ds.UDP != nil && ds.UDP.BackendOverride != "" {
+			if _, _, err := net.SplitHostPort(ds.UDP.BackendOverride); err != nil {
+				add(field+".udp.backend_override", "%q is not a host:port address", ds.UDP.BackendOverride)
+			}
+		}
+
+		if rm := ds.RouterMiddlewares; rm != nil {
+			switch rm.Unmapped {
+			case "", UnmappedMiddlewaresDrop, UnmappedMiddlewaresKeep:
+			default:
+				add(field+".router_middlewares.unmapped", "unknown value %q (expected drop or keep)", rm.Unmapped)
+			}
+			for name := range rm.Map {
+				if name == "" {
+					add(field+".router_middlewares.map", "middleware names must not be empty")
+				}
+			}
+		}
+
+		for j, status := range ds.IncludeStatus {
+			switch status {
+			case "enabled", "warning", "disabled":
+			default:
+				add(fmt.Sprintf("%s.include_status[%d]", field, j), "unknown status %q (expected enabled, warning or disabled)", status)
+			}
+		}
+
+		if ds.Passthrough {
+			for _, option := range passthroughIgnoredOptions {
+				if option.isSet(ds) {
+					add(field+"."+option.field, "cannot be combined with passthrough, which serves the downstream config unchanged")
+				}
+			}
+		}
+	}
+
+	checkDuration("poll_interval", c.PollInterval)
+	checkDuration("http_timeout", c.HTTPTimeout)
+
+	for _, name := range sortedKeys(c.Middlewares) {
+		field := "middlewares." + name
+		if strings.Contains(name, "@") {
+			add(field, "name must not contain @; downstreams reference it by its plain name")
+		}
+		if len(c.Middlewares[name]) != 1 {
+			add(field, "must define exactly one middleware type (e.g. headers, basicAuth), got %d", len(c.Middlewares[name]))
+		}
+	}
+
+	if c.MaxConcurrency < 0 {
+		add("max_concurrency", "must not be negative")
+	}
+	if _, err := ParseLogLevel(c.LogLevel); err != nil {
+		add("log_level", "%v", err)
+	}
+	if _, err := parseLogFormat(c.LogFormat); err != nil {
+		add("log_format", "%v", err)
+	}
+
+	if c.OutputFile != nil {
+		if c.OutputFile.Path == "" {
+			add("output_file.path", "is required")
+		}
+		if c.OutputFile.Format != "" {
+			if _, err := ParseFormat(c.OutputFile.Format); err != nil {
+				add("output_file.format", "%v", err)
+			}
+		}
+	}
+
+	if c.Health != nil && (c.Health.MinHealthyPercent < 0 || c.Health.MinHealthyPercent > 100) {
+		add("health.min_healthy_percent", "must be between 0 and 100")
+	}
+
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			add("listen", "%q is not a host:port address (e.g. :8080)", c.Listen)
+		}
+	}
+
+	// The files themselves are loaded by BuildServerTLSConfig at startup, so a
+	// config can be validated where the deployment certificates do not exist
+	if c.ServerTLS != nil {
+		if c.ServerTLS.CertFile == "" {
+			add("server_tls.cert_file", "is required")
+		}
+		if c.ServerTLS.KeyFile == "" {
+			add("server_tls.key_file", "is required")
+		}
+	}
+
+	if c.Auth != nil {
+		basic := c.Auth.Username != "" || c.Auth.Password != ""
+		switch {
+		case c.Auth.BearerToken != "" && basic:
+			add("auth", "set either bearer_token or username/password, not both")
+		case c.Auth.BearerToken == "" && !basic:
+			add("auth", "requires bearer_token or username/password")
+		case basic && c.Auth.Username == "":
+			add("auth.username", "is required with password")
+		case basic && c.Auth.Password == "":
+			add("auth.password", "is required with username")
+		}
+	}
+
+	return errs
+}
+
+// checkHTTPURL reports whether rawURL is an absolute http or https URL
+func checkHTTPURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("%q is not an http(s) URL", rawURL)
+	}
+	return nil
+}
+
+// checkBackendOverride reports whether value is an http(s) URL or a host[:port],
+// to which GetBackendURL adds the protocol
+func checkBackendOverride(value string) error {
+	if strings.Contains(value, "://") {
+		return checkHTTPURL(value)
+	}
+	if err := checkHTTPURL("http://" + value); err != nil {
+		return fmt.Errorf("%q is not an http(s) URL or host[:port]", value)
+	}
+	return nil
+}
+
+// unknownFieldPattern matches the errors yaml.v3 reports for keys without a struct field
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (\S+) not found in type \S+$`)
+
+// typeErrorPattern extracts the line number from other yaml.v3 decoding errors
+var typeErrorPattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// decodeErrors converts the per-field errors of a yaml.TypeError into ValidationErrors
+func decodeErrors(typeErr *yaml.TypeError) ValidationErrors {
+	errs := make(ValidationErrors, 0, len(typeErr.Errors))
+	for _, message := range typeErr.Errors {
+		if match := unknownFieldPattern.FindStringSubmatch(message); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			errs = append(errs, ValidationError{Field: match[2], Line: line, Message: "unknown field"})
+		} else if match := typeErrorPattern.FindStringSubmatch(message); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			errs = append(errs, ValidationError{Line: line, Message: match[2]})
+		} else {
+			errs = append(errs, ValidationError{Message: message})
+		}
+	}
+	return errs
+}
+
+// fieldPathPattern splits a field path like "downstream[1].tls" into its segments
+var fieldPathPattern = regexp.MustCompile(`([^.\[\]]+)|\[(\d+)\]`)
+
+// lineOf returns the line of the YAML node at a field path such as "downstream[1].api_url".
+// If the field itself is absent, the line of its closest present parent is returned.
+func lineOf(root *yaml.Node, field string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+
+	for _, match := range fieldPathPattern.FindAllStringSubmatch(field, -1) {
+		var next *yaml.Node
+		nextLine := 0
+		switch {
+		case match[2] != "" && node.Kind == yaml.SequenceNode:
+			if index, _ := strconv.Atoi(match[2]); index < len(node.Content) {
+				next = node.Content[index]
+				nextLine = next.Line
+			}
+		case match[1] != "" && node.Kind == yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == match[1] {
+					next = node.Content[i+1]
+					nextLine = node.Content[i].Line
+					break
+				}
+			}
+		}
+		if next == nil {
+			return line
+		}
+		node, line = next, nextLine
+	}
+	return line
+}
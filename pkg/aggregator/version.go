@@ -0,0 +1,92 @@
+package aggregator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ConfigVersion identifies the content of the cached configuration
+type ConfigVersion struct {
+	// Hash is a hex-encoded SHA-256 of the JSON encoding of the configuration
+	Hash string
+	// Modified is when the configuration content last changed
+	Modified time.Time
+}
+
+// ETag returns a strong entity tag for the configuration encoded in the given format
+func (v ConfigVersion) ETag(format Format) string {
+	return `"` + v.Hash[:32] + "-" + string(format) + `"`
+}
+
+// MatchesETag reports whether an If-None-Match header value matches etag.
+// Weak comparison is used, as required for If-None-Match.
+func MatchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConfigVersion returns the version of the current cached configuration (thread-safe)
+func (a *Aggregator) GetConfigVersion() ConfigVersion {
+	a.configMutex.RLock()
+	defer a.configMutex.RUnlock()
+	return a.version
+}
+
+// GetEncodedConfig returns the current cached configuration encoded in the given format
+// together with its version. Encodings are cached until the configuration changes, so
+// repeated polls do not re-encode the whole configuration.
+func (a *Aggregator) GetEncodedConfig(format Format) ([]byte, ConfigVersion, error) {
+	a.configMutex.RLock()
+	data, ok := a.encoded[format]
+	version := a.version
+	a.configMutex.RUnlock()
+	if ok {
+		return data, version, nil
+	}
+
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+
+	// Another request may have encoded it, or the config may have been swapped meanwhile
+	if data, ok := a.encoded[format]; ok {
+		return data, a.version, nil
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeConfig(&buf, a.cachedConfig, format); err != nil {
+		return nil, a.version, err
+	}
+	a.encoded[format] = buf.Bytes()
+	return buf.Bytes(), a.version, nil
+}
+
+// setCachedConfig swaps in a new cached configuration and recomputes its version.
+// The modification time only advances when the content actually changed.
+func (a *Aggregator) setCachedConfig(config HTTPProxyConfig) {
+	var buf bytes.Buffer
+	if err := EncodeConfig(&buf, config, FormatJSON); err != nil {
+		a.log().Error("Error encoding aggregated config", "error", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	hash := hex.EncodeToString(sum[:])
+
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+
+	a.cachedConfig = config
+	a.encoded = map[Format][]byte{FormatJSON: buf.Bytes()}
+	if hash != a.version.Hash {
+		a.version = ConfigVersion{Hash: hash, Modified: time.Now().UTC().Truncate(time.Second)}
+	}
+}
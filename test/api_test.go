@@ -1,8 +1,12 @@
 package aggregator_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"traefik-config-middleware/pkg/aggregator"
@@ -36,7 +40,7 @@ func TestFetchDownstreamRouters_Success(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	routers, err := aggregator.FetchDownstreamRouters(ds, client)
+	routers, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchDownstreamRouters failed: %v", err)
 	}
@@ -67,7 +71,7 @@ func TestFetchDownstreamRouters_WithAPIKey(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchDownstreamRouters failed: %v", err)
 	}
@@ -96,7 +100,7 @@ func TestFetchDownstreamRouters_NoAPIKey(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchDownstreamRouters failed: %v", err)
 	}
@@ -119,7 +123,7 @@ func TestFetchDownstreamRouters_Non200Status(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for non-200 status, got nil")
 	}
@@ -138,7 +142,7 @@ func TestFetchDownstreamRouters_404Status(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for 404 status, got nil")
 	}
@@ -158,7 +162,7 @@ func TestFetchDownstreamRouters_InvalidJSON(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for invalid JSON, got nil")
 	}
@@ -178,7 +182,7 @@ func TestFetchDownstreamRouters_EmptyArray(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	routers, err := aggregator.FetchDownstreamRouters(ds, client)
+	routers, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchDownstreamRouters failed: %v", err)
 	}
@@ -195,7 +199,7 @@ func TestFetchDownstreamRouters_InvalidAPIURL(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for invalid API URL, got nil")
 	}
@@ -208,7 +212,7 @@ func TestFetchDownstreamRouters_NetworkError(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for network failure, got nil")
 	}
@@ -250,7 +254,7 @@ func TestFetchDownstreamRouters_MultipleRouters(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	routers, err := aggregator.FetchDownstreamRouters(ds, client)
+	routers, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchDownstreamRouters failed: %v", err)
 	}
@@ -279,7 +283,7 @@ func TestFetchDownstreamRouters_LongErrorBody(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchDownstreamRouters(ds, client)
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for non-200 status, got nil")
 	}
@@ -289,3 +293,129 @@ func TestFetchDownstreamRouters_LongErrorBody(t *testing.T) {
 		t.Error("error message should be truncated for long responses")
 	}
 }
+
+// Helper to create a mock Traefik API server that paginates like the real API
+func createPaginatedTraefikServer(t *testing.T, routers []aggregator.TraefikRouter) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if page < 1 || perPage < 1 {
+			t.Errorf("expected page and per_page query params, got '%s'", r.URL.RawQuery)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		start := (page - 1) * perPage
+		end := start + perPage
+		if end > len(routers) {
+			end = len(routers)
+		}
+		nextPage := 1
+		if page*perPage < len(routers) {
+			nextPage = page + 1
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Next-Page", strconv.Itoa(nextPage))
+		json.NewEncoder(w).Encode(routers[start:end])
+	}))
+}
+
+func generateRouters(count int) []aggregator.TraefikRouter {
+	routers := make([]aggregator.TraefikRouter, count)
+	for i := range routers {
+		routers[i] = aggregator.TraefikRouter{
+			Name:        fmt.Sprintf("router%d@kubernetes", i),
+			EntryPoints: []string{"websecure"},
+			Service:     fmt.Sprintf("service%d", i),
+			Rule:        fmt.Sprintf("Host(`app%d.example.com`)", i),
+		}
+	}
+	return routers
+}
+
+func TestFetchDownstreamRouters_FollowsPagination(t *testing.T) {
+	server := createPaginatedTraefikServer(t, generateRouters(250))
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{
+		Name:   "test-downstream",
+		APIURL: server.URL,
+	}
+
+	client := &http.Client{}
+	routers, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
+	if err != nil {
+		t.Fatalf("FetchDownstreamRouters failed: %v", err)
+	}
+
+	if len(routers) != 250 {
+		t.Fatalf("expected 250 routers, got %d", len(routers))
+	}
+	if routers[249].Name != "router249@kubernetes" {
+		t.Errorf("expected last router 'router249@kubernetes', got '%s'", routers[249].Name)
+	}
+}
+
+func TestFetchDownstreamRouters_ErrorOnLaterPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{
+		Name:   "test-downstream",
+		APIURL: server.URL,
+	}
+
+	client := &http.Client{}
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
+	if err == nil {
+		t.Error("expected error when a later page fails, got nil")
+	}
+}
+
+func TestFetchDownstreamRouters_InvalidNextPageHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Next-Page", "not-a-number")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{
+		Name:   "test-downstream",
+		APIURL: server.URL,
+	}
+
+	client := &http.Client{}
+	_, err := aggregator.FetchDownstreamRouters(context.Background(), ds, client)
+	if err == nil {
+		t.Error("expected error for invalid X-Next-Page header, got nil")
+	}
+}
+
+func TestAggregateConfigs_RouterCounts(t *testing.T) {
+	server := createPaginatedTraefikServer(t, generateRouters(150))
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "big-cluster", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if got := agg.RouterCounts()["big-cluster"]; got != 150 {
+		t.Errorf("expected router count 150, got %d", got)
+	}
+	if got := len(agg.GetCachedConfig().HTTP.Routers); got != 150 {
+		t.Errorf("expected 150 aggregated routers, got %d", got)
+	}
+}
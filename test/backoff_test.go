@@ -0,0 +1,158 @@
+package aggregator_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestRetryBackoff_GrowsWithJitter(t *testing.T) {
+	ds := aggregator.DownstreamConfig{Retry: &aggregator.RetryConfig{
+		Attempts:       5,
+		InitialBackoff: "100ms",
+		MaxBackoff:     "350ms",
+	}}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 350 * time.Millisecond},
+		{10, 350 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			backoff := ds.RetryBackoff(tt.retry)
+			if backoff > tt.max || backoff < tt.max/2 {
+				t.Fatalf("retry %d: backoff %v outside [%v, %v]", tt.retry, backoff, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryAttempts_Default(t *testing.T) {
+	if attempts := (aggregator.DownstreamConfig{}).RetryAttempts(); attempts != 1 {
+		t.Errorf("expected a single attempt without retry config, got %d", attempts)
+	}
+}
+
+func TestAggregateConfigs_RetriesWithinCycle(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first two requests, then recover
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[{"name":"app@kubernetes","entryPoints":["websecure"],"rule":"Host(` + "`app.example.com`" + `)"}]`))
+	}))
+	defer server.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{
+			Name:   "flaky",
+			APIURL: server.URL,
+			Retry:  &aggregator.RetryConfig{Attempts: 3, InitialBackoff: "1ms", MaxBackoff: "5ms"},
+		}},
+	}, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if _, ok := agg.GetCachedConfig().HTTP.Routers["flaky-app"]; !ok {
+		t.Error("expected router after successful retry")
+	}
+	status := agg.Status().Downstreams[0]
+	if status.Attempts != 3 || status.LastError != "" {
+		t.Errorf("expected success on the third attempt, got %+v", status)
+	}
+}
+
+func TestAggregateConfigs_CircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{
+			Name:           "down",
+			APIURL:         server.URL,
+			CircuitBreaker: &aggregator.CircuitBreakerConfig{FailureThreshold: 2, Cooldown: "50ms"},
+		}},
+	}, &http.Client{})
+
+	agg.AggregateConfigs(context.Background())
+	if status := agg.Status().Downstreams[0]; status.Circuit != aggregator.CircuitClosed || status.ConsecutiveFailures != 1 {
+		t.Fatalf("expected closed circuit after one failure, got %+v", status)
+	}
+
+	agg.AggregateConfigs(context.Background())
+	status := agg.Status().Downstreams[0]
+	if status.Circuit != aggregator.CircuitOpen || status.CircuitOpenUntil == nil {
+		t.Fatalf("expected open circuit after two failures, got %+v", status)
+	}
+
+	// While open, the downstream is not contacted and keeps its last real error
+	agg.AggregateConfigs(context.Background())
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected no request while the circuit is open, got %d requests", got)
+	}
+	if errs := agg.DownstreamErrors(); !strings.Contains(errs["down"].Error(), "502") {
+		t.Errorf("expected last fetch error to be kept, got %v", errs["down"])
+	}
+
+	// After the cool-down a single trial request is let through
+	time.Sleep(60 * time.Millisecond)
+	if status := agg.Status().Downstreams[0]; status.Circuit != aggregator.CircuitHalfOpen {
+		t.Errorf("expected half-open circuit after cool-down, got %s", status.Circuit)
+	}
+	failing.Store(false)
+	agg.AggregateConfigs(context.Background())
+
+	status = agg.Status().Downstreams[0]
+	if requests.Load() != 3 || status.Circuit != aggregator.CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("expected circuit to close after a successful trial, got %+v", status)
+	}
+
+	metrics := scrapeMetrics(t, agg)
+	if !strings.Contains(metrics, `downstream_fetch_total{downstream="down",result="circuit_open"} 1`) {
+		t.Error("expected circuit_open fetch result in metrics")
+	}
+}
+
+func TestAggregateConfigs_CircuitOpenError(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{
+			Name:           "down",
+			APIURL:         "http://127.0.0.1:1",
+			CircuitBreaker: &aggregator.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: "1h"},
+		}},
+	}, &http.Client{})
+
+	agg.AggregateConfigs(context.Background())
+	agg.AggregateConfigs(context.Background())
+
+	if err := agg.DownstreamErrors()["down"]; err == nil || errors.Is(err, aggregator.ErrCircuitOpen) {
+		t.Errorf("expected the connection error to be reported, got %v", err)
+	}
+	if circuit := agg.Status().Downstreams[0].Circuit; circuit != aggregator.CircuitOpen {
+		t.Errorf("expected circuit to be open, got %s", circuit)
+	}
+}
@@ -0,0 +1,185 @@
+package aggregator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+// Helper to create a mock Traefik API server that can be switched to failing
+func createFlakyTraefikServer(t *testing.T, routers []aggregator.TraefikRouter, failing *atomic.Bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path != "/api/http/routers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routers)
+	}))
+}
+
+var staleTestRouters = []aggregator.TraefikRouter{
+	{
+		Name:        "app-router@kubernetes",
+		EntryPoints: []string{"websecure"},
+		Service:     "app-service",
+		Rule:        "Host(`app.example.com`)",
+	},
+}
+
+func TestStaleTTLDuration(t *testing.T) {
+	tests := []struct {
+		ttl      string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"5m", 5 * time.Minute},
+		{"invalid", 0},
+		{"-1s", 0},
+	}
+
+	for _, tt := range tests {
+		ds := aggregator.DownstreamConfig{StaleTTL: tt.ttl}
+		if got := ds.StaleTTLDuration(); got != tt.expected {
+			t.Errorf("StaleTTLDuration(%q) = %v, expected %v", tt.ttl, got, tt.expected)
+		}
+	}
+}
+
+func TestAggregateConfigs_StaleConfigServedOnFailure(t *testing.T) {
+	var failing atomic.Bool
+	server := createFlakyTraefikServer(t, staleTestRouters, &failing)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL, StaleTTL: "1h"},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if len(agg.StaleDownstreams()) != 0 {
+		t.Errorf("expected no stale downstreams, got %v", agg.StaleDownstreams())
+	}
+
+	failing.Store(true)
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+	if _, exists := cachedConfig.HTTP.Routers["test-downstream-app-router"]; !exists {
+		t.Error("expected router from last known good config to be kept")
+	}
+	if _, exists := cachedConfig.HTTP.Services["service-test-downstream-app-router"]; !exists {
+		t.Error("expected service from last known good config to be kept")
+	}
+
+	stale := agg.StaleDownstreams()
+	if len(stale) != 1 || stale[0] != "test-downstream" {
+		t.Errorf("expected stale downstreams ['test-downstream'], got %v", stale)
+	}
+
+	// Recovery clears the stale flag
+	failing.Store(false)
+	agg.AggregateConfigs(context.Background())
+
+	if len(agg.StaleDownstreams()) != 0 {
+		t.Errorf("expected no stale downstreams after recovery, got %v", agg.StaleDownstreams())
+	}
+}
+
+func TestAggregateConfigs_StaleConfigExpires(t *testing.T) {
+	var failing atomic.Bool
+	server := createFlakyTraefikServer(t, staleTestRouters, &failing)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL, StaleTTL: "10ms"},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	failing.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+	if len(cachedConfig.HTTP.Routers) != 0 {
+		t.Errorf("expected expired stale config to be dropped, got %d routers", len(cachedConfig.HTTP.Routers))
+	}
+	if len(agg.StaleDownstreams()) != 0 {
+		t.Errorf("expected no stale downstreams after expiry, got %v", agg.StaleDownstreams())
+	}
+}
+
+func TestAggregateConfigs_StaleConfigExpiresWhileNotDue(t *testing.T) {
+	var failing atomic.Bool
+	server := createFlakyTraefikServer(t, staleTestRouters, &failing)
+	defer server.Close()
+	var devRequests atomic.Int32
+	dev := createCountingTraefikServer(t, "dev.example.com", &devRequests)
+	defer dev.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "dev", APIURL: dev.URL, PollInterval: "20ms"},
+			{Name: "prod", APIURL: server.URL, StaleTTL: "100ms"},
+		},
+		PollInterval: "1h",
+	}, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	// The failed fetch in Run serves prod stale, and prod is not due again for an hour
+	failing.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agg.Run(ctx)
+
+	expired := waitFor(t, time.Second, func() bool {
+		_, ok := agg.GetCachedConfig().HTTP.Routers["prod-app-router"]
+		return !ok && len(agg.StaleDownstreams()) == 0
+	})
+	if !expired {
+		t.Errorf("expected stale config of the not due downstream to expire, stale: %v", agg.StaleDownstreams())
+	}
+	if _, ok := agg.GetCachedConfig().HTTP.Routers["dev-app"]; !ok {
+		t.Error("expected router of the refreshed downstream")
+	}
+}
+
+func TestAggregateConfigs_StaleDisabledByDefault(t *testing.T) {
+	var failing atomic.Bool
+	server := createFlakyTraefikServer(t, staleTestRouters, &failing)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	failing.Store(true)
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+	if len(cachedConfig.HTTP.Routers) != 0 {
+		t.Errorf("expected no routers without stale_ttl, got %d", len(cachedConfig.HTTP.Routers))
+	}
+}
@@ -0,0 +1,176 @@
+package aggregator_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+// Helper to create a mock Traefik API server that responds after a delay
+func createSlowTraefikServer(t *testing.T, delay time.Duration, routers []aggregator.TraefikRouter) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routers)
+	}))
+}
+
+func TestAggregateConfigs_FetchesDownstreamsConcurrently(t *testing.T) {
+	var downstreams []aggregator.DownstreamConfig
+	for i := 0; i < 5; i++ {
+		server := createSlowTraefikServer(t, 100*time.Millisecond, []aggregator.TraefikRouter{
+			{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+		})
+		defer server.Close()
+		downstreams = append(downstreams, aggregator.DownstreamConfig{
+			Name:   fmt.Sprintf("downstream%d", i),
+			APIURL: server.URL,
+		})
+	}
+
+	cfg := &aggregator.Config{Downstream: downstreams}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+
+	start := time.Now()
+	agg.AggregateConfigs(context.Background())
+	elapsed := time.Since(start)
+
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("expected downstreams to be fetched in parallel, took %v", elapsed)
+	}
+	if got := len(agg.GetCachedConfig().HTTP.Routers); got != 5 {
+		t.Errorf("expected 5 routers, got %d", got)
+	}
+}
+
+func TestAggregateConfigs_MaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if current <= peak || maxInFlight.CompareAndSwap(peak, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	var downstreams []aggregator.DownstreamConfig
+	for i := 0; i < 6; i++ {
+		downstreams = append(downstreams, aggregator.DownstreamConfig{
+			Name:   fmt.Sprintf("downstream%d", i),
+			APIURL: server.URL,
+		})
+	}
+
+	cfg := &aggregator.Config{Downstream: downstreams, MaxConcurrency: 2}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if peak := maxInFlight.Load(); peak > 2 {
+		t.Errorf("expected at most 2 concurrent fetches, got %d", peak)
+	}
+}
+
+func TestAggregateConfigs_PerDownstreamTimeout(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	slowServer := createSlowTraefikServer(t, 2*time.Second, routers)
+	defer slowServer.Close()
+	fastServer := createMockTraefikServer(t, routers)
+	defer fastServer.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "slow", APIURL: slowServer.URL, Timeout: "50ms"},
+			{Name: "fast", APIURL: fastServer.URL},
+		},
+	}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+
+	start := time.Now()
+	agg.AggregateConfigs(context.Background())
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected slow downstream to time out, took %v", elapsed)
+	}
+
+	cachedConfig := agg.GetCachedConfig()
+	if _, exists := cachedConfig.HTTP.Routers["fast-app"]; !exists {
+		t.Error("expected router from fast downstream")
+	}
+	if _, exists := cachedConfig.HTTP.Routers["slow-app"]; exists {
+		t.Error("expected slow downstream to be skipped after timeout")
+	}
+}
+
+func TestAggregateConfigs_CancelledContextKeepsCachedConfig(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	var slow atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(routers)
+	}))
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	slow.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	agg.AggregateConfigs(ctx)
+
+	if got := len(agg.GetCachedConfig().HTTP.Routers); got != 1 {
+		t.Errorf("expected cached config to survive cancelled aggregation, got %d routers", got)
+	}
+}
+
+func TestFetchDownstreamRouters_ContextCancelled(t *testing.T) {
+	server := createSlowTraefikServer(t, 2*time.Second, nil)
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{Name: "test-downstream", APIURL: server.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := aggregator.FetchDownstreamRouters(ctx, ds, &http.Client{}); err == nil {
+		t.Error("expected error for cancelled context, got nil")
+	}
+}
+
+func TestConfig_MaxConcurrencyLimit(t *testing.T) {
+	if got := (&aggregator.Config{}).MaxConcurrencyLimit(); got != 8 {
+		t.Errorf("expected default max concurrency 8, got %d", got)
+	}
+	if got := (&aggregator.Config{MaxConcurrency: 3}).MaxConcurrencyLimit(); got != 3 {
+		t.Errorf("expected max concurrency 3, got %d", got)
+	}
+}
@@ -1,8 +1,10 @@
 package aggregator_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"traefik-config-middleware/pkg/aggregator"
@@ -117,3 +119,287 @@ func TestLoadConfig_MultipleDownstreams(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate_ValidFile(t *testing.T) {
+	cfg, err := aggregator.LoadConfig(filepath.Join("testdata", "valid_config.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   aggregator.Config
+		expected string
+	}{
+		{
+			name:     "no downstream",
+			config:   aggregator.Config{},
+			expected: "downstream: at least one downstream is required",
+		},
+		{
+			name: "missing name",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{APIURL: "http://traefik:8080"},
+			}},
+			expected: "downstream[0].name: is required",
+		},
+		{
+			name: "duplicate name",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik-1:8080"},
+				{Name: "a", APIURL: "http://traefik-2:8080"},
+			}},
+			expected: `downstream[1].name: duplicate name "a" (also used by downstream[0])`,
+		},
+		{
+			name: "invalid api_url",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "traefik:8080"},
+			}},
+			expected: "is not an http(s) URL",
+		},
+		{
+			name: "invalid poll interval",
+			config: aggregator.Config{
+				Downstream:   []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				PollInterval: "soon",
+			},
+			expected: `poll_interval: invalid duration "soon"`,
+		},
+		{
+			name: "negative timeout",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", Timeout: "-5s"},
+			}},
+			expected: "downstream[0].timeout: must be positive",
+		},
+		{
+			name: "passthrough with wildcard_fix",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", Passthrough: true, WildcardFix: true},
+			}},
+			expected: "downstream[0].wildcard_fix: cannot be combined with passthrough",
+		},
+		{
+			name: "invalid tcp backend override",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", TCP: &aggregator.TCPConfig{BackendOverride: "traefik"}},
+			}},
+			expected: "downstream[0].tcp.backend_override",
+		},
+		{
+			name: "udp without backend",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", UDP: &aggregator.UDPConfig{Enabled: true}},
+			}},
+			expected: "downstream[0].udp: requires backends or backend_override",
+		},
+		{
+			name: "invalid tcp backend",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", TCP: &aggregator.TCPConfig{Backends: map[string]string{"postgres": "5432"}}},
+			}},
+			expected: `downstream[0].tcp.backends.postgres: "5432" is not a host:port or :port address`,
+		},
+		{
+			name: "unknown unmapped middleware policy",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", RouterMiddlewares: &aggregator.RouterMiddlewaresConfig{Unmapped: "rename"}},
+			}},
+			expected: `downstream[0].router_middlewares.unmapped: unknown value "rename"`,
+		},
+		{
+			name: "unknown include status",
+			config: aggregator.Config{Downstream: []aggregator.DownstreamConfig{
+				{Name: "a", APIURL: "http://traefik:8080", IncludeStatus: []string{"enabled", "ok"}},
+			}},
+			expected: `downstream[0].include_status[1]: unknown status "ok"`,
+		},
+		{
+			name: "middleware with two types",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				Middlewares: map[string]map[string]interface{}{
+					"edge": {"headers": map[string]interface{}{}, "compress": map[string]interface{}{}},
+				},
+			},
+			expected: "middlewares.edge: must define exactly one middleware type",
+		},
+		{
+			name: "bearer and basic auth",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				Auth:       &aggregator.AuthConfig{BearerToken: "token", Username: "user", Password: "pw"},
+			},
+			expected: "auth: set either bearer_token or username/password, not both",
+		},
+		{
+			name: "missing server certificate",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				ServerTLS:  &aggregator.ServerTLSConfig{KeyFile: "server.key"},
+			},
+			expected: "server_tls.cert_file: is required",
+		},
+		{
+			name: "invalid listen address",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				Listen:     "8080",
+			},
+			expected: "listen:",
+		},
+		{
+			name: "health percent out of range",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				Health:     &aggregator.HealthConfig{MinHealthyPercent: 150},
+			},
+			expected: "health.min_healthy_percent: must be between 0 and 100",
+		},
+		{
+			name: "unknown log level",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				LogLevel:   "loud",
+			},
+			expected: "log_level",
+		},
+		{
+			name: "unknown output format",
+			config: aggregator.Config{
+				Downstream: []aggregator.DownstreamConfig{{Name: "a", APIURL: "http://traefik:8080"}},
+				OutputFile: &aggregator.OutputFileConfig{Path: "out.xml", Format: "xml"},
+			},
+			expected: "output_file.format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_ReportsAllProblemsWithLines(t *testing.T) {
+	path := writeConfigFile(t, `downstream:
+  - name: cluster-a
+    api_url: http://traefik-a:8080
+    ignore_entrypoint:
+      - traefik
+  - name: cluster-a
+    api_url: traefik-b:8080
+    passthrough: true
+    wildcard_fix: true
+  - api_url: http://traefik-c:8080
+poll_interval: 30 seconds
+`)
+
+	_, err := aggregator.LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+
+	var validationErrs aggregator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	expected := []string{
+		"line 4: ignore_entrypoint: unknown field",
+		`line 6: downstream[1].name: duplicate name "cluster-a" (also used by downstream[0])`,
+		`line 7: downstream[1].api_url: "traefik-b:8080" is not an http(s) URL`,
+		"line 9: downstream[1].wildcard_fix: cannot be combined with passthrough",
+		"line 10: downstream[2].name: is required",
+		`line 11: poll_interval: invalid duration "30 seconds"`,
+	}
+	if len(validationErrs) != len(expected) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(expected), len(validationErrs), err)
+	}
+	for i, want := range expected {
+		if got := validationErrs[i].Error(); !strings.HasPrefix(got, want) {
+			t.Errorf("problem %d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestLoadConfig_ServerTLSFilesNotRequired(t *testing.T) {
+	path := writeConfigFile(t, `downstream:
+  - name: cluster-a
+    api_url: http://traefik-a:8080
+server_tls:
+  cert_file: /etc/certs/does-not-exist.crt
+  key_file: /etc/certs/does-not-exist.key
+  client_ca_file: /etc/certs/does-not-exist-ca.crt
+`)
+
+	if _, err := aggregator.LoadConfig(path); err != nil {
+		t.Errorf("expected certificate files not to be checked during validation, got %v", err)
+	}
+}
+
+func TestLoadConfig_SchemelessBackendOverride(t *testing.T) {
+	path := writeConfigFile(t, `downstream:
+  - name: cluster-a
+    api_url: http://traefik-a:8080
+    backend_override: custom-backend:9000
+`)
+
+	cfg, err := aggregator.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := aggregator.GetBackendURL(cfg.Downstream[0], false); got != "http://custom-backend:9000" {
+		t.Errorf("expected backend 'http://custom-backend:9000', got '%s'", got)
+	}
+}
+
+func TestLoadConfig_InvalidBackendOverride(t *testing.T) {
+	path := writeConfigFile(t, `downstream:
+  - name: cluster-a
+    api_url: http://traefik-a:8080
+    backend_override: ftp://backend:21
+`)
+
+	_, err := aggregator.LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "downstream[0].backend_override") {
+		t.Errorf("expected backend_override error, got %v", err)
+	}
+}
+
+func TestLoadConfig_TypeMismatch(t *testing.T) {
+	path := writeConfigFile(t, `downstream:
+  - name: cluster-a
+    api_url: http://traefik-a:8080
+max_concurrency: many
+`)
+
+	_, err := aggregator.LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected error for invalid max_concurrency, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 4:") {
+		t.Errorf("expected error to reference line 4, got %q", err.Error())
+	}
+}
@@ -0,0 +1,183 @@
+package aggregator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func sampleProxyConfig() aggregator.HTTPProxyConfig {
+	config := aggregator.HTTPProxyConfig{}
+	config.HTTP.Routers = map[string]aggregator.HTTPRouter{
+		"prod-app": {
+			Rule:        "Host(`app.example.com`)",
+			Service:     "service-prod-app",
+			EntryPoints: []string{"websecure"},
+			Middlewares: []string{"auth@file"},
+			TLS: map[string]interface{}{
+				"certResolver": "letsencrypt",
+				"domains":      []aggregator.TLSDomain{{Main: "app.example.com", Sans: []string{"www.example.com"}}},
+			},
+		},
+	}
+	config.HTTP.Services = map[string]aggregator.HTTPService{
+		"service-prod-app": {
+			LoadBalancer: aggregator.LoadBalancer{
+				Servers: []aggregator.Server{{URL: "https://traefik.internal:443"}},
+			},
+		},
+	}
+	config.HTTP.Middlewares = map[string]interface{}{}
+	config.TCP.Routers = map[string]aggregator.TCPRouter{}
+	config.TCP.Services = map[string]aggregator.TCPService{}
+	config.UDP.Routers = map[string]aggregator.UDPRouter{}
+	config.UDP.Services = map[string]aggregator.UDPService{}
+	return config
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]aggregator.Format{
+		"json": aggregator.FormatJSON,
+		"YAML": aggregator.FormatYAML,
+		"yml":  aggregator.FormatYAML,
+		"toml": aggregator.FormatTOML,
+	}
+	for name, expected := range tests {
+		got, err := aggregator.ParseFormat(name)
+		if err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", name, err)
+		}
+		if got != expected {
+			t.Errorf("ParseFormat(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+
+	if _, err := aggregator.ParseFormat("xml"); err == nil {
+		t.Error("expected error for unsupported format, got nil")
+	}
+}
+
+func TestFormatFromAccept(t *testing.T) {
+	tests := map[string]aggregator.Format{
+		"":                                    aggregator.FormatJSON,
+		"*/*":                                 aggregator.FormatJSON,
+		"application/yaml":                    aggregator.FormatYAML,
+		"text/html, application/x-yaml;q=0.9": aggregator.FormatYAML,
+		"application/toml":                    aggregator.FormatTOML,
+		"application/json, application/yaml":  aggregator.FormatJSON,
+
+		"application/yaml;q=0, application/json":         aggregator.FormatJSON,
+		"application/json;q=0.5, application/toml":       aggregator.FormatTOML,
+		"application/json;q=0.8, application/yaml;q=0.9": aggregator.FormatYAML,
+		"text/html, */*;q=0.1, application/yaml;q=0.5":   aggregator.FormatYAML,
+		"application/yaml;q=0":                           aggregator.FormatJSON,
+	}
+	for accept, expected := range tests {
+		if got := aggregator.FormatFromAccept(accept); got != expected {
+			t.Errorf("FormatFromAccept(%q) = %q, expected %q", accept, got, expected)
+		}
+	}
+}
+
+func TestEncodeConfig_YAMLMatchesJSON(t *testing.T) {
+	config := sampleProxyConfig()
+
+	var jsonBuf, yamlBuf bytes.Buffer
+	if err := aggregator.EncodeConfig(&jsonBuf, config, aggregator.FormatJSON); err != nil {
+		t.Fatalf("EncodeConfig json failed: %v", err)
+	}
+	if err := aggregator.EncodeConfig(&yamlBuf, config, aggregator.FormatYAML); err != nil {
+		t.Fatalf("EncodeConfig yaml failed: %v", err)
+	}
+
+	var fromJSON, fromYAML map[string]interface{}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if err := yaml.Unmarshal(yamlBuf.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("invalid YAML output: %v", err)
+	}
+
+	// Round-trip the YAML through JSON so both sides use the same Go types
+	normalized, _ := json.Marshal(fromYAML)
+	fromYAML = nil
+	json.Unmarshal(normalized, &fromYAML)
+
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Errorf("YAML output differs from JSON output:\n%s", yamlBuf.String())
+	}
+	if !bytes.Contains(yamlBuf.Bytes(), []byte("entryPoints:")) {
+		t.Error("expected YAML output to keep camelCase key 'entryPoints'")
+	}
+}
+
+func TestEncodeConfig_TOML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := aggregator.EncodeConfig(&buf, sampleProxyConfig(), aggregator.FormatTOML); err != nil {
+		t.Fatalf("EncodeConfig toml failed: %v", err)
+	}
+
+	expected := `[http.routers.prod-app]
+entryPoints = ["websecure"]
+middlewares = ["auth@file"]
+rule = "Host(` + "`app.example.com`" + `)"
+service = "service-prod-app"
+
+[http.routers.prod-app.tls]
+certResolver = "letsencrypt"
+
+[[http.routers.prod-app.tls.domains]]
+main = "app.example.com"
+sans = ["www.example.com"]
+
+[[http.services.service-prod-app.loadBalancer.servers]]
+url = "https://traefik.internal:443"
+
+[tcp.routers]
+
+[tcp.services]
+
+[udp.routers]
+
+[udp.services]
+
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected TOML output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestEncodeConfig_StableOutput(t *testing.T) {
+	config := sampleProxyConfig()
+	config.HTTP.Routers["a-router"] = aggregator.HTTPRouter{Rule: "Host(`a.example.com`)", Service: "a"}
+	config.HTTP.Routers["z-router"] = aggregator.HTTPRouter{Rule: "Host(`z.example.com`)", Service: "z"}
+
+	for _, format := range []aggregator.Format{aggregator.FormatJSON, aggregator.FormatYAML, aggregator.FormatTOML} {
+		var first, second bytes.Buffer
+		aggregator.EncodeConfig(&first, config, format)
+		aggregator.EncodeConfig(&second, config, format)
+		if first.String() != second.String() {
+			t.Errorf("expected stable %s output across encodings", format)
+		}
+	}
+}
+
+func TestEncodeConfig_TOMLQuotesKeys(t *testing.T) {
+	config := sampleProxyConfig()
+	config.HTTP.Routers = map[string]aggregator.HTTPRouter{
+		"router@kubernetes": {Rule: "Host(`a.example.com`)", Service: "a"},
+	}
+
+	var buf bytes.Buffer
+	if err := aggregator.EncodeConfig(&buf, config, aggregator.FormatTOML); err != nil {
+		t.Fatalf("EncodeConfig toml failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`[http.routers."router@kubernetes"]`)) {
+		t.Errorf("expected quoted table key, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestReady_AfterFirstAggregation(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{Name: "test-ds", APIURL: server.URL}},
+	}, &http.Client{})
+
+	if err := agg.Ready(); err == nil {
+		t.Error("expected not ready before the first aggregation")
+	}
+
+	agg.AggregateConfigs(context.Background())
+
+	if err := agg.Ready(); err != nil {
+		t.Errorf("expected ready after aggregation, got %v", err)
+	}
+}
+
+func TestReady_CancelledAggregationDoesNotCount(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{Name: "test-ds", APIURL: "http://127.0.0.1:1"}},
+	}, &http.Client{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	agg.AggregateConfigs(ctx)
+
+	if err := agg.Ready(); err == nil {
+		t.Error("expected not ready after a cancelled aggregation")
+	}
+}
+
+func TestReady_MinHealthyPercent(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+
+	downstreams := []aggregator.DownstreamConfig{
+		{Name: "working", APIURL: server.URL},
+		{Name: "failing", APIURL: failingServer.URL},
+	}
+
+	tests := []struct {
+		percent int
+		ready   bool
+	}{
+		{0, true},
+		{50, true},
+		{51, false},
+		{100, false},
+	}
+
+	for _, tt := range tests {
+		agg := aggregator.NewAggregator(&aggregator.Config{
+			Downstream: downstreams,
+			Health:     &aggregator.HealthConfig{MinHealthyPercent: tt.percent},
+		}, &http.Client{})
+		agg.AggregateConfigs(context.Background())
+
+		err := agg.Ready()
+		if (err == nil) != tt.ready {
+			t.Errorf("min_healthy_percent %d: expected ready=%v, got %v", tt.percent, tt.ready, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "1 of 2 downstreams healthy") {
+			t.Errorf("min_healthy_percent %d: unexpected reason %q", tt.percent, err.Error())
+		}
+	}
+}
+
+func TestLive_DetectsStalledPolling(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream:   []aggregator.DownstreamConfig{{Name: "test-ds", APIURL: server.URL}},
+		PollInterval: "10ms",
+	}, &http.Client{})
+
+	// Within the grace period before the first aggregation
+	if err := agg.Live(); err != nil {
+		t.Errorf("expected live right after creation, got %v", err)
+	}
+
+	agg.AggregateConfigs(context.Background())
+	if err := agg.Live(); err != nil {
+		t.Errorf("expected live after aggregation, got %v", err)
+	}
+
+	// Nothing polls anymore, so three intervals later the loop counts as stuck
+	time.Sleep(50 * time.Millisecond)
+	if err := agg.Live(); err == nil {
+		t.Error("expected not live once aggregations stopped")
+	}
+}
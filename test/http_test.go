@@ -1,6 +1,7 @@
 package aggregator_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -46,7 +47,7 @@ func TestGetCachedConfig_ReturnsJSON(t *testing.T) {
 	}
 
 	agg := createTestAggregator(cfg)
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	result := agg.GetCachedConfig()
 
@@ -64,7 +65,7 @@ func TestGetCachedConfig_EmptyConfig(t *testing.T) {
 	}
 
 	agg := createTestAggregator(cfg)
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	result := agg.GetCachedConfig()
 
@@ -110,7 +111,7 @@ func TestGetCachedConfig_WithTLS(t *testing.T) {
 	}
 
 	agg := createTestAggregator(cfg)
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	result := agg.GetCachedConfig()
 
@@ -151,7 +152,7 @@ func TestGetCachedConfig_WithMiddlewares(t *testing.T) {
 	}
 
 	agg := createTestAggregator(cfg)
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	result := agg.GetCachedConfig()
 
@@ -1,6 +1,7 @@
 package aggregator_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -14,13 +15,20 @@ import (
 
 // Helper to create a mock Traefik API server
 func createMockTraefikServer(t *testing.T, routers []aggregator.TraefikRouter) *httptest.Server {
+	return createTraefikAPIServer(t, map[string]interface{}{"/api/http/routers": routers})
+}
+
+// createTraefikAPIServer creates a mock Traefik API that serves the response for each
+// path as JSON and 404 for any other path
+func createTraefikAPIServer(t *testing.T, responses map[string]interface{}) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/api/http/routers" {
+		response, ok := responses[r.URL.Path]
+		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(routers)
+		json.NewEncoder(w).Encode(response)
 	}))
 }
 
@@ -49,7 +57,7 @@ func TestAggregateConfigs_SingleDownstream(t *testing.T) {
 
 	// Create aggregator and run aggregation
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	// Verify results
 	cachedConfig := agg.GetCachedConfig()
@@ -101,7 +109,7 @@ func TestAggregateConfigs_MultipleDownstreams(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -139,7 +147,7 @@ func TestAggregateConfigs_DownstreamError(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -172,7 +180,7 @@ func TestAggregateConfigs_EntryPointOverride(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -205,7 +213,7 @@ func TestAggregateConfigs_MiddlewareInjection(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -244,7 +252,7 @@ func TestAggregateConfigs_TLSWithCertResolver(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -286,7 +294,7 @@ func TestAggregateConfigs_IgnoreEntryPoints(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -331,7 +339,7 @@ func TestAggregateConfigs_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			agg.AggregateConfigs()
+			agg.AggregateConfigs(context.Background())
 		}()
 	}
 
@@ -379,7 +387,7 @@ func TestAggregateConfigs_BackendURLConstruction(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -416,7 +424,7 @@ func TestAggregateConfigs_ServerTransport(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -449,7 +457,7 @@ func TestAggregateConfigs_ServerTransportNotSet(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -486,7 +494,7 @@ func TestAggregateConfigs_WildcardFix(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -547,7 +555,7 @@ poll_interval: 10s
 
 	// Create aggregator and aggregate configs
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	// Verify results
 	result := agg.GetCachedConfig()
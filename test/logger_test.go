@@ -0,0 +1,123 @@
+package aggregator_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{"", slog.LevelWarn, false},
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelWarn, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			level, err := aggregator.ParseLogLevel(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLogLevel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if level != tt.expected {
+				t.Errorf("ParseLogLevel(%q) = %v, want %v", tt.input, level, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewLogger_InvalidFormat(t *testing.T) {
+	if _, err := aggregator.NewLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("Expected error for unknown log format")
+	}
+}
+
+func TestAggregator_LogLevels(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+		{Name: "dashboard@internal", EntryPoints: []string{"traefik"}, Rule: "PathPrefix(`/dashboard`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-ds", APIURL: server.URL, IgnoreEntryPoints: []string{"traefik"}},
+			{Name: "broken", APIURL: "http://127.0.0.1:1"},
+		},
+	}
+
+	var warnBuf bytes.Buffer
+	warnLogger, err := aggregator.NewLogger(&warnBuf, "warn", "json")
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.SetLogger(warnLogger)
+	agg.AggregateConfigs(context.Background())
+
+	records := decodeLogRecords(t, &warnBuf)
+	if len(records) != 1 {
+		t.Fatalf("Expected only the fetch error at warn level, got %d records: %v", len(records), records)
+	}
+	if records[0]["level"] != "ERROR" || records[0]["downstream"] != "broken" || records[0]["error"] == nil {
+		t.Errorf("Unexpected error record: %v", records[0])
+	}
+
+	var debugBuf bytes.Buffer
+	debugLogger, err := aggregator.NewLogger(&debugBuf, "debug", "json")
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	agg.SetLogger(debugLogger)
+	agg.AggregateConfigs(context.Background())
+
+	var added, skipped bool
+	for _, record := range decodeLogRecords(t, &debugBuf) {
+		if record["level"] != "DEBUG" {
+			continue
+		}
+		switch record["msg"] {
+		case "Added HTTP route":
+			added = record["downstream"] == "test-ds" && record["router"] == "app@kubernetes"
+		case "Skipping router":
+			skipped = record["router"] == "dashboard@internal" && record["reason"] == "ignored_entrypoint"
+		}
+	}
+	if !added {
+		t.Error("Expected debug record for the added route")
+	}
+	if !skipped {
+		t.Error("Expected debug record for the skipped route")
+	}
+}
+
+func decodeLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
@@ -0,0 +1,121 @@
+package aggregator_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func scrapeMetrics(t *testing.T, agg *aggregator.Aggregator) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := agg.Metrics().Write(&buf); err != nil {
+		t.Fatalf("writing metrics failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestMetrics_DownstreamFetch(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "dashboard@internal", EntryPoints: []string{"traefik"}, Rule: "PathPrefix(`/dashboard`)"},
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "working", APIURL: server.URL, IgnoreEntryPoints: []string{"traefik"}},
+			{Name: "failing", APIURL: failingServer.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	agg.AggregateConfigs(context.Background())
+
+	output := scrapeMetrics(t, agg)
+
+	expected := []string{
+		`traefik_config_middleware_downstream_fetch_total{downstream="working",result="success"} 2`,
+		`traefik_config_middleware_downstream_fetch_total{downstream="failing",result="failure"} 2`,
+		`traefik_config_middleware_downstream_fetch_duration_seconds_count{downstream="working"} 2`,
+		`traefik_config_middleware_downstream_fetch_duration_seconds_bucket{downstream="working",le="+Inf"} 2`,
+		`traefik_config_middleware_downstream_routers{downstream="working",protocol="http"} 1`,
+		`traefik_config_middleware_downstream_services{downstream="working",protocol="http"} 1`,
+		`traefik_config_middleware_downstream_middlewares{downstream="working"} 0`,
+		`traefik_config_middleware_downstream_skipped_routers{downstream="working",reason="ignored_entrypoint"} 1`,
+		`traefik_config_middleware_aggregation_duration_seconds_count 2`,
+		`# TYPE traefik_config_middleware_downstream_fetch_total counter`,
+		`# TYPE traefik_config_middleware_aggregation_duration_seconds histogram`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", line, output)
+		}
+	}
+
+	if !strings.Contains(output, `traefik_config_middleware_downstream_last_success_timestamp_seconds{downstream="working"}`) {
+		t.Error("expected last success timestamp for working downstream")
+	}
+	if strings.Contains(output, `traefik_config_middleware_downstream_last_success_timestamp_seconds{downstream="failing"}`) {
+		t.Error("expected no last success timestamp for failing downstream")
+	}
+}
+
+func TestMetrics_ConfigRequests(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{}, &http.Client{})
+
+	agg.Metrics().ObserveConfigRequest(aggregator.FormatJSON, http.StatusOK)
+	agg.Metrics().ObserveConfigRequest(aggregator.FormatJSON, http.StatusOK)
+	agg.Metrics().ObserveConfigRequest(aggregator.FormatYAML, http.StatusNotModified)
+
+	output := scrapeMetrics(t, agg)
+
+	for _, line := range []string{
+		`traefik_config_middleware_config_requests_total{format="json",code="200"} 2`,
+		`traefik_config_middleware_config_requests_total{format="yaml",code="304"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", line, output)
+		}
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{}, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	recorder := httptest.NewRecorder()
+	agg.Metrics().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", recorder.Code)
+	}
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("expected Prometheus text content type, got '%s'", ct)
+	}
+	if !strings.Contains(recorder.Body.String(), "traefik_config_middleware_aggregation_duration_seconds_count 1") {
+		t.Errorf("expected aggregation count in output, got:\n%s", recorder.Body.String())
+	}
+}
+
+func TestMetrics_EscapesLabelValues(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{}, &http.Client{})
+	agg.Metrics().ObserveConfigRequest(aggregator.Format(`we"ird\`), http.StatusOK)
+
+	output := scrapeMetrics(t, agg)
+	if !strings.Contains(output, `format="we\"ird\\"`) {
+		t.Errorf("expected escaped label value, got:\n%s", output)
+	}
+}
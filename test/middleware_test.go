@@ -0,0 +1,279 @@
+package aggregator_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestRouterMiddlewares_WithoutMappingUsesConfiguredOnly(t *testing.T) {
+	ds := aggregator.DownstreamConfig{Middlewares: []string{"auth@file"}}
+	router := aggregator.TraefikRouter{Middlewares: []string{"networking-traefik-geoblock@kubernetescrd"}}
+
+	got := aggregator.RouterMiddlewares(ds, router, nil)
+
+	if !reflect.DeepEqual(got, []string{"auth@file"}) {
+		t.Errorf("expected [auth@file], got %v", got)
+	}
+}
+
+func TestRouterMiddlewares_MapsAndDrops(t *testing.T) {
+	ds := aggregator.DownstreamConfig{
+		Middlewares: []string{"auth@file"},
+		RouterMiddlewares: &aggregator.RouterMiddlewaresConfig{
+			Map: map[string]string{
+				"networking-traefik-geoblock@kubernetescrd": "geoblock@file",
+				"internal-auth@kubernetescrd":               "",
+			},
+		},
+	}
+	router := aggregator.TraefikRouter{Middlewares: []string{
+		"networking-traefik-geoblock@kubernetescrd",
+		"internal-auth@kubernetescrd",
+		"compress@kubernetescrd",
+	}}
+
+	got := aggregator.RouterMiddlewares(ds, router, nil)
+
+	want := []string{"auth@file", "geoblock@file"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRouterMiddlewares_KeepsUnmappedAndDeduplicates(t *testing.T) {
+	ds := aggregator.DownstreamConfig{
+		Middlewares: []string{"auth@file"},
+		RouterMiddlewares: &aggregator.RouterMiddlewaresConfig{
+			Map:      map[string]string{"sso@kubernetescrd": "auth@file"},
+			Unmapped: aggregator.UnmappedMiddlewaresKeep,
+		},
+	}
+	router := aggregator.TraefikRouter{Middlewares: []string{"sso@kubernetescrd", "compress@file"}}
+
+	got := aggregator.RouterMiddlewares(ds, router, nil)
+
+	want := []string{"auth@file", "compress@file"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAggregateConfigs_MapsRouterMiddlewares(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "app@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "app",
+			Rule:        "Host(`app.example.com`)",
+			Middlewares: []string{"networking-traefik-geoblock@kubernetescrd"},
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "cluster",
+				APIURL: server.URL,
+				RouterMiddlewares: &aggregator.RouterMiddlewaresConfig{
+					Map: map[string]string{"networking-traefik-geoblock@kubernetescrd": "geoblock@file"},
+				},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	router, ok := agg.GetCachedConfig().HTTP.Routers["cluster-app"]
+	if !ok {
+		t.Fatal("expected router 'cluster-app' to exist")
+	}
+	if !reflect.DeepEqual(router.Middlewares, []string{"geoblock@file"}) {
+		t.Errorf("expected middlewares [geoblock@file], got %v", router.Middlewares)
+	}
+}
+
+func TestTraefikMiddleware_UnmarshalKeepsDefinition(t *testing.T) {
+	data := []byte(`{
+		"redirectScheme": {"scheme": "https", "permanent": true},
+		"status": "enabled",
+		"usedBy": ["app@kubernetescrd"],
+		"name": "redirect@kubernetescrd",
+		"provider": "kubernetescrd",
+		"type": "redirectscheme"
+	}`)
+
+	var middleware aggregator.TraefikMiddleware
+	if err := json.Unmarshal(data, &middleware); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if middleware.Name != "redirect@kubernetescrd" || middleware.Type != "redirectscheme" || middleware.Status != "enabled" {
+		t.Errorf("unexpected middleware %+v", middleware)
+	}
+	want := map[string]interface{}{
+		"redirectScheme": map[string]interface{}{"scheme": "https", "permanent": true},
+	}
+	if !reflect.DeepEqual(middleware.Config, want) {
+		t.Errorf("expected config %v, got %v", want, middleware.Config)
+	}
+}
+
+func TestShouldReplicateMiddleware_TypeFilters(t *testing.T) {
+	headers := aggregator.TraefikMiddleware{Name: "headers@file", Type: "headers"}
+	forwardAuth := aggregator.TraefikMiddleware{Name: "sso@kubernetescrd", Type: "forwardauth"}
+
+	all := &aggregator.ReplicateConfig{Enabled: true}
+	if !aggregator.ShouldReplicateMiddleware(headers, all) || !aggregator.ShouldReplicateMiddleware(forwardAuth, all) {
+		t.Error("expected all types to be replicated without filters")
+	}
+
+	excluded := &aggregator.ReplicateConfig{Enabled: true, ExcludeTypes: []string{"forwardAuth"}}
+	if aggregator.ShouldReplicateMiddleware(forwardAuth, excluded) {
+		t.Error("expected excluded type not to be replicated")
+	}
+	if !aggregator.ShouldReplicateMiddleware(headers, excluded) {
+		t.Error("expected other types to be replicated")
+	}
+
+	only := &aggregator.ReplicateConfig{Enabled: true, Types: []string{"headers"}}
+	if !aggregator.ShouldReplicateMiddleware(headers, only) || aggregator.ShouldReplicateMiddleware(forwardAuth, only) {
+		t.Error("expected only listed types to be replicated")
+	}
+
+	if aggregator.ShouldReplicateMiddleware(headers, &aggregator.ReplicateConfig{}) {
+		t.Error("expected nothing to be replicated when disabled")
+	}
+}
+
+func TestAggregateConfigs_ReplicatesMiddlewares(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "app@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "app",
+			Rule:        "Host(`app.example.com`)",
+			Middlewares: []string{"web-headers@kubernetescrd", "web-sso@kubernetescrd", "web-geoblock@kubernetescrd"},
+		},
+	}
+	middlewares := []aggregator.TraefikMiddleware{
+		{
+			Name:   "web-headers@kubernetescrd",
+			Type:   "headers",
+			Status: "enabled",
+			Config: map[string]interface{}{"headers": map[string]interface{}{"stsSeconds": float64(31536000)}},
+		},
+		{
+			Name:   "web-sso@kubernetescrd",
+			Type:   "forwardauth",
+			Status: "enabled",
+			Config: map[string]interface{}{"forwardAuth": map[string]interface{}{"address": "http://sso.auth.svc"}},
+		},
+		{
+			Name:   "web-geoblock@kubernetescrd",
+			Type:   "plugin",
+			Status: "enabled",
+			Config: map[string]interface{}{"plugin": map[string]interface{}{"geoblock": map[string]interface{}{}}},
+		},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers":     routers,
+		"/api/http/middlewares": middlewares,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "cluster",
+				APIURL: server.URL,
+				Replicate: &aggregator.ReplicateConfig{
+					Enabled:      true,
+					ExcludeTypes: []string{"forwardAuth"},
+				},
+				RouterMiddlewares: &aggregator.RouterMiddlewaresConfig{
+					Map: map[string]string{"web-geoblock@kubernetescrd": "geoblock@file"},
+				},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	config := agg.GetCachedConfig()
+
+	if _, ok := config.HTTP.Middlewares["cluster-web-headers"]; !ok {
+		t.Errorf("expected middleware 'cluster-web-headers' to be replicated, got %v", config.HTTP.Middlewares)
+	}
+	if _, ok := config.HTTP.Middlewares["cluster-web-sso"]; ok {
+		t.Error("expected excluded forwardAuth middleware not to be replicated")
+	}
+
+	router := config.HTTP.Routers["cluster-app"]
+	want := []string{"cluster-web-headers", "geoblock@file"}
+	if !reflect.DeepEqual(router.Middlewares, want) {
+		t.Errorf("expected middlewares %v, got %v", want, router.Middlewares)
+	}
+}
+
+func TestAggregateConfigs_EmitsConfiguredMiddlewares(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "app@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "app",
+			Rule:        "Host(`app.example.com`)",
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	path := writeConfigFile(t, `downstream:
+  - name: cluster
+    api_url: `+server.URL+`
+    middlewares:
+      - security-headers
+middlewares:
+  security-headers:
+    headers:
+      stsSeconds: 31536000
+      frameDeny: true
+`)
+	cfg, err := aggregator.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	config := agg.GetCachedConfig()
+
+	if router := config.HTTP.Routers["cluster-app"]; !reflect.DeepEqual(router.Middlewares, []string{"security-headers"}) {
+		t.Errorf("expected middlewares [security-headers], got %v", router.Middlewares)
+	}
+
+	var buf bytes.Buffer
+	if err := aggregator.EncodeConfig(&buf, config, aggregator.FormatJSON); err != nil {
+		t.Fatalf("EncodeConfig failed: %v", err)
+	}
+	var encoded struct {
+		HTTP struct {
+			Middlewares map[string]map[string]map[string]interface{} `json:"middlewares"`
+		} `json:"http"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &encoded); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	headers := encoded.HTTP.Middlewares["security-headers"]["headers"]
+	if headers["stsSeconds"] != float64(31536000) || headers["frameDeny"] != true {
+		t.Errorf("expected headers definition to be emitted unchanged, got %v", encoded.HTTP.Middlewares)
+	}
+}
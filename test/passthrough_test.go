@@ -1,6 +1,7 @@
 package aggregator_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -47,7 +48,7 @@ func TestFetchPassthroughConfig_Success(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	config, err := aggregator.FetchPassthroughConfig(ds, client)
+	config, err := aggregator.FetchPassthroughConfig(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchPassthroughConfig failed: %v", err)
 	}
@@ -78,7 +79,7 @@ func TestFetchPassthroughConfig_WithAPIKey(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchPassthroughConfig(ds, client)
+	_, err := aggregator.FetchPassthroughConfig(context.Background(), ds, client)
 	if err != nil {
 		t.Fatalf("FetchPassthroughConfig failed: %v", err)
 	}
@@ -103,7 +104,7 @@ func TestFetchPassthroughConfig_Non200Status(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchPassthroughConfig(ds, client)
+	_, err := aggregator.FetchPassthroughConfig(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for non-200 status, got nil")
 	}
@@ -124,7 +125,7 @@ func TestFetchPassthroughConfig_InvalidJSON(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	_, err := aggregator.FetchPassthroughConfig(ds, client)
+	_, err := aggregator.FetchPassthroughConfig(context.Background(), ds, client)
 	if err == nil {
 		t.Error("expected error for invalid JSON, got nil")
 	}
@@ -165,7 +166,7 @@ func TestAggregateConfigs_Passthrough(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -232,7 +233,7 @@ func TestAggregateConfigs_PassthroughWithMultipleRouters(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -294,7 +295,7 @@ func TestAggregateConfigs_MixedPassthroughAndRegular(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -348,7 +349,7 @@ func TestAggregateConfigs_PassthroughError(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -399,7 +400,7 @@ func TestAggregateConfigs_PassthroughPreservesConfig(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -476,7 +477,7 @@ func TestAggregateConfigs_PassthroughWithMiddlewares(t *testing.T) {
 	}
 
 	agg := aggregator.NewAggregator(cfg, &http.Client{})
-	agg.AggregateConfigs()
+	agg.AggregateConfigs(context.Background())
 
 	cachedConfig := agg.GetCachedConfig()
 
@@ -0,0 +1,69 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func writeRecording(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordedTransport_AggregatesRecordings(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, dir, "prod/api/http/routers.json",
+		`[{"name":"app@kubernetes","entryPoints":["websecure"],"service":"app","rule":"Host(`+"`app.example.com`"+`)"}]`)
+	writeRecording(t, dir, "legacy/config.json",
+		`{"http":{"routers":{"web":{"rule":"Host(`+"`legacy.example.com`"+`)","service":"web","entryPoints":["web"]}}}}`)
+
+	downstreams := []aggregator.DownstreamConfig{
+		{Name: "prod", APIURL: "http://traefik-prod:8080"},
+		{Name: "legacy", APIURL: "http://legacy.example.com/api/config", Passthrough: true},
+		{Name: "unrecorded", APIURL: "http://traefik-dev:8080"},
+	}
+	client := &http.Client{Transport: aggregator.NewRecordedTransport(dir, downstreams)}
+
+	agg := aggregator.NewAggregator(&aggregator.Config{Downstream: downstreams}, client)
+	agg.AggregateConfigs(context.Background())
+
+	routers := agg.GetCachedConfig().HTTP.Routers
+	if router, ok := routers["prod-app"]; !ok || router.Rule != "Host(`app.example.com`)" {
+		t.Errorf("expected router from prod recording, got %v", routers)
+	}
+	if _, ok := routers["legacy-web"]; !ok {
+		t.Errorf("expected router from passthrough recording, got %v", routers)
+	}
+
+	services := agg.GetCachedConfig().HTTP.Services
+	if url := services["service-prod-app"].LoadBalancer.Servers[0].URL; !strings.Contains(url, "traefik-prod") {
+		t.Errorf("expected backend derived from api_url, got %s", url)
+	}
+
+	errs := agg.DownstreamErrors()
+	if len(errs) != 1 || errs["unrecorded"] == nil {
+		t.Fatalf("expected only the unrecorded downstream to fail, got %v", errs)
+	}
+	if !strings.Contains(errs["unrecorded"].Error(), "404") {
+		t.Errorf("expected 404 for missing recording, got %v", errs["unrecorded"])
+	}
+}
+
+func TestRecordedTransport_UnknownHost(t *testing.T) {
+	client := &http.Client{Transport: aggregator.NewRecordedTransport(t.TempDir(), nil)}
+	if _, err := client.Get("http://unknown:8080/api/http/routers"); err == nil {
+		t.Error("expected error for request to unconfigured downstream")
+	}
+}
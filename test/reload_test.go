@@ -0,0 +1,93 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestSetConfig_SwapsDownstreams(t *testing.T) {
+	routersA := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`a.example.com`)"},
+	}
+	routersB := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`b.example.com`)"},
+	}
+	serverA := createMockTraefikServer(t, routersA)
+	defer serverA.Close()
+	serverB := createMockTraefikServer(t, routersB)
+	defer serverB.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{Name: "cluster-a", APIURL: serverA.URL}},
+	}, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if _, ok := agg.GetCachedConfig().HTTP.Routers["cluster-a-app"]; !ok {
+		t.Fatal("expected router from cluster-a before reload")
+	}
+
+	agg.SetConfig(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{Name: "cluster-b", APIURL: serverB.URL}},
+	})
+
+	// The previous aggregation keeps being served until the next cycle
+	if _, ok := agg.GetCachedConfig().HTTP.Routers["cluster-a-app"]; !ok {
+		t.Error("expected cached config to survive SetConfig")
+	}
+
+	agg.AggregateConfigs(context.Background())
+
+	routers := agg.GetCachedConfig().HTTP.Routers
+	if _, ok := routers["cluster-a-app"]; ok {
+		t.Error("expected router from removed downstream to be gone")
+	}
+	if _, ok := routers["cluster-b-app"]; !ok {
+		t.Error("expected router from added downstream")
+	}
+	if _, ok := agg.RouterCounts()["cluster-a"]; ok {
+		t.Error("expected router count of removed downstream to be dropped")
+	}
+	if metrics := scrapeMetrics(t, agg); strings.Contains(metrics, `downstream="cluster-a"`) {
+		t.Error("expected metrics of removed downstream to be dropped")
+	}
+}
+
+func TestWatchConfigFile_NotifiesOnContentChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("poll_interval: 30s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 1)
+	go aggregator.WatchConfigFile(ctx, path, 10*time.Millisecond, changes)
+
+	// Give the watcher time to record the initial content, then rewrite it unchanged
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("poll_interval: 30s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("expected no notification for identical content")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("poll_interval: 10s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected notification after content change")
+	}
+}
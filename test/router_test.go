@@ -1,6 +1,9 @@
 package aggregator_test
 
 import (
+	"context"
+	"math"
+	"net/http"
 	"testing"
 
 	"traefik-config-middleware/pkg/aggregator"
@@ -124,3 +127,142 @@ func TestShouldIgnoreRouter_EmptyRouterEntryPoints(t *testing.T) {
 		t.Error("expected router NOT to be ignored when it has no entrypoints")
 	}
 }
+
+func TestRouterPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority int
+		rule     string
+		offset   int
+		expected int
+	}{
+		{name: "default priority", rule: "Host(`a.example.com`)", expected: 0},
+		{name: "explicit priority", priority: 42, rule: "Host(`a.example.com`)", expected: 42},
+		{name: "offset on explicit priority", priority: 42, rule: "Host(`a.example.com`)", offset: 100, expected: 142},
+		{name: "offset on rule length", rule: "Host(`a.example.com`)", offset: 100, expected: 121},
+		{name: "negative offset stays positive", priority: 10, offset: -100, expected: 1},
+		{name: "large priority saturates", priority: math.MaxInt - 1, offset: 100, expected: math.MaxInt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := aggregator.DownstreamConfig{PriorityOffset: tt.offset}
+			router := aggregator.TraefikRouter{Rule: tt.rule, Priority: tt.priority}
+
+			if got := aggregator.RouterPriority(ds, router); got != tt.expected {
+				t.Errorf("expected priority %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAggregateConfigs_PreservesRouterPriority(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "api@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "api",
+			Rule:        "Host(`example.com`) && PathPrefix(`/api`)",
+			Priority:    500,
+		},
+		{
+			Name:        "web@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "web",
+			Rule:        "Host(`example.com`)",
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "primary", APIURL: server.URL, PriorityOffset: 1000},
+			{Name: "secondary", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	config := agg.GetCachedConfig()
+
+	expected := map[string]int{
+		"primary-api":   1500,
+		"primary-web":   1000 + len("Host(`example.com`)"),
+		"secondary-api": 500,
+		"secondary-web": 0,
+	}
+	for name, priority := range expected {
+		if got := config.HTTP.Routers[name].Priority; got != priority {
+			t.Errorf("router %s: expected priority %d, got %d", name, priority, got)
+		}
+	}
+}
+
+func TestRouterStatusIncluded(t *testing.T) {
+	include := []string{"enabled", "warning"}
+
+	if !aggregator.RouterStatusIncluded(aggregator.TraefikRouter{Status: "warning"}, include) {
+		t.Error("expected warning router to be included")
+	}
+	if aggregator.RouterStatusIncluded(aggregator.TraefikRouter{Status: "disabled"}, include) {
+		t.Error("expected disabled router to be excluded")
+	}
+	if !aggregator.RouterStatusIncluded(aggregator.TraefikRouter{}, include) {
+		t.Error("expected router without status to be included")
+	}
+	if !aggregator.RouterStatusIncluded(aggregator.TraefikRouter{Status: "disabled"}, nil) {
+		t.Error("expected every router to be included without include_status")
+	}
+}
+
+func TestAggregateConfigs_SkipsRoutersByStatus(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "web@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "web",
+			Rule:        "Host(`web.example.com`)",
+			Status:      "enabled",
+		},
+		{
+			Name:        "shop@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "shop",
+			Rule:        "Host(`shop.example.com`)",
+			Status:      "disabled",
+			Error:       []string{`the service "shop@kubernetescrd" does not exist`},
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "cluster", APIURL: server.URL, IncludeStatus: []string{"enabled", "warning"}},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	config := agg.GetCachedConfig()
+	if _, ok := config.HTTP.Routers["cluster-web"]; !ok {
+		t.Error("expected enabled router to be promoted")
+	}
+	if _, ok := config.HTTP.Routers["cluster-shop"]; ok {
+		t.Error("expected disabled router to be skipped")
+	}
+
+	status := agg.Status().Downstreams[0]
+	if status.SkippedByReason["status_disabled"] != 1 {
+		t.Errorf("expected 1 router skipped as status_disabled, got %v", status.SkippedByReason)
+	}
+	if len(status.SkippedRouters) != 1 {
+		t.Fatalf("expected 1 skipped router, got %v", status.SkippedRouters)
+	}
+	skipped := status.SkippedRouters[0]
+	if skipped.Name != "shop@kubernetescrd" || skipped.Reason != "status_disabled" || len(skipped.Errors) != 1 {
+		t.Errorf("unexpected skipped router %+v", skipped)
+	}
+}
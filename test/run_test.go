@@ -0,0 +1,126 @@
+package aggregator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestRun_PollsUntilCancelled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		json.NewEncoder(w).Encode([]aggregator.TraefikRouter{
+			{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream:   []aggregator.DownstreamConfig{{Name: "test-ds", APIURL: server.URL}},
+		PollInterval: "20ms",
+	}
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		agg.Run(ctx)
+		close(done)
+	}()
+
+	if !waitFor(t, time.Second, func() bool { return requests.Load() >= 3 }) {
+		t.Fatalf("expected repeated polling, got %d requests", requests.Load())
+	}
+	if _, ok := agg.GetCachedConfig().HTTP.Routers["test-ds-app"]; !ok {
+		t.Error("expected aggregated router")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return after cancellation")
+	}
+}
+
+func TestRun_CancelAbortsInFlightFetch(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{{Name: "slow", APIURL: server.URL}},
+	}, &http.Client{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		agg.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return without waiting for the downstream")
+	}
+}
+
+func TestRun_SetConfigAggregatesImmediately(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	// A poll interval far beyond the test duration, so only SetConfig can trigger the second run
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream:   []aggregator.DownstreamConfig{{Name: "first", APIURL: server.URL}},
+		PollInterval: "1h",
+	}, &http.Client{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agg.Run(ctx)
+
+	if !waitFor(t, time.Second, func() bool { _, ok := agg.GetCachedConfig().HTTP.Routers["first-app"]; return ok }) {
+		t.Fatal("expected initial aggregation")
+	}
+
+	agg.SetConfig(&aggregator.Config{
+		Downstream:   []aggregator.DownstreamConfig{{Name: "second", APIURL: server.URL}},
+		PollInterval: "1h",
+	})
+
+	if !waitFor(t, time.Second, func() bool { _, ok := agg.GetCachedConfig().HTTP.Routers["second-app"]; return ok }) {
+		t.Fatal("expected aggregation right after SetConfig")
+	}
+}
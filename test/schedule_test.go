@@ -0,0 +1,81 @@
+package aggregator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+// createCountingTraefikServer serves a single router and counts the requests it receives
+func createCountingTraefikServer(t *testing.T, host string, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		json.NewEncoder(w).Encode([]aggregator.TraefikRouter{
+			{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`" + host + "`)"},
+		})
+	}))
+}
+
+func TestDownstreamPollInterval(t *testing.T) {
+	cfg := &aggregator.Config{PollInterval: "30s"}
+
+	if got := cfg.DownstreamPollInterval(aggregator.DownstreamConfig{}); got != 30*time.Second {
+		t.Errorf("expected global interval, got %v", got)
+	}
+	if got := cfg.DownstreamPollInterval(aggregator.DownstreamConfig{PollInterval: "5m"}); got != 5*time.Minute {
+		t.Errorf("expected downstream interval, got %v", got)
+	}
+	if got := cfg.DownstreamPollInterval(aggregator.DownstreamConfig{PollInterval: "bogus"}); got != 30*time.Second {
+		t.Errorf("expected fallback to global interval, got %v", got)
+	}
+}
+
+func TestRun_SchedulesDownstreamsIndependently(t *testing.T) {
+	var fastRequests, slowRequests atomic.Int32
+	fast := createCountingTraefikServer(t, "dev.example.com", &fastRequests)
+	defer fast.Close()
+	slow := createCountingTraefikServer(t, "prod.example.com", &slowRequests)
+	defer slow.Close()
+
+	agg := aggregator.NewAggregator(&aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "dev", APIURL: fast.URL, PollInterval: "20ms"},
+			{Name: "prod", APIURL: slow.URL},
+		},
+		PollInterval: "1h",
+	}, &http.Client{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agg.Run(ctx)
+
+	if !waitFor(t, time.Second, func() bool { return fastRequests.Load() >= 4 }) {
+		t.Fatalf("expected dev to be polled repeatedly, got %d requests", fastRequests.Load())
+	}
+	if got := slowRequests.Load(); got != 1 {
+		t.Errorf("expected prod to be polled once, got %d requests", got)
+	}
+
+	// Refreshing dev re-merges prod's previous routers
+	routers := agg.GetCachedConfig().HTTP.Routers
+	if _, ok := routers["prod-app"]; !ok {
+		t.Error("expected router of the not yet due downstream to be kept")
+	}
+	if _, ok := routers["dev-app"]; !ok {
+		t.Error("expected router of the refreshed downstream")
+	}
+
+	status := agg.Status()
+	if status.Downstreams[0].PollIntervalSeconds != 0.02 || status.Downstreams[1].PollIntervalSeconds != 3600 {
+		t.Errorf("unexpected poll intervals in status: %+v", status.Downstreams)
+	}
+	if status.Downstreams[1].NextPoll == nil || time.Until(*status.Downstreams[1].NextPoll) < 59*time.Minute {
+		t.Errorf("expected prod to be due in about an hour, got %v", status.Downstreams[1].NextPoll)
+	}
+}
@@ -0,0 +1,282 @@
+package aggregator_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+// testCert is a generated certificate together with its PEM files on disk
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// generateCert creates a certificate signed by parent, or a self-signed CA when parent is nil
+func generateCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key, certFile: certFile, keyFile: keyFile}
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestListenAddr(t *testing.T) {
+	if addr := (&aggregator.Config{}).ListenAddr(); addr != ":8080" {
+		t.Errorf("expected default :8080, got %s", addr)
+	}
+	if addr := (&aggregator.Config{Listen: "127.0.0.1:9000"}).ListenAddr(); addr != "127.0.0.1:9000" {
+		t.Errorf("expected configured address, got %s", addr)
+	}
+}
+
+func TestRequireAuth_Bearer(t *testing.T) {
+	handler := aggregator.RequireAuth(&aggregator.AuthConfig{BearerToken: "s3cret"}, okHandler())
+
+	tests := []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{"valid token", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"basic instead of bearer", "Basic czNjcmV0Og==", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/traefik-config", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+			if rec.Code == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("expected Bearer challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequireAuth_Basic(t *testing.T) {
+	handler := aggregator.RequireAuth(&aggregator.AuthConfig{Username: "traefik", Password: "pw"}, okHandler())
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		expected int
+	}{
+		{"valid credentials", "traefik", "pw", http.StatusOK},
+		{"wrong password", "traefik", "wrong", http.StatusUnauthorized},
+		{"wrong username", "admin", "pw", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/traefik-config", nil)
+			req.SetBasicAuth(tt.username, tt.password)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/traefik-config", nil))
+	if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic") {
+		t.Errorf("expected Basic challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestRequireAuth_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	aggregator.RequireAuth(nil, okHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/traefik-config", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 without auth config, got %d", rec.Code)
+	}
+}
+
+func TestBuildServerTLSConfig_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := generateCert(t, dir, "ca", nil)
+	serverCert := generateCert(t, dir, "server", ca)
+	clientCert := generateCert(t, dir, "client", ca)
+
+	serverTLS := &aggregator.ServerTLSConfig{
+		CertFile:     serverCert.certFile,
+		KeyFile:      serverCert.keyFile,
+		ClientCAFile: ca.certFile,
+	}
+	tlsConfig, err := aggregator.BuildServerTLSConfig(serverTLS)
+	if err != nil {
+		t.Fatalf("BuildServerTLSConfig failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/traefik-config", aggregator.RequireClientCert(serverTLS, okHandler()))
+	mux.Handle("/ready", okHandler())
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	get := func(client *http.Client, path string) int {
+		t.Helper()
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Without a client certificate, probes succeed but the config is refused
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if code := get(anonymous, "/ready"); code != http.StatusOK {
+		t.Errorf("expected /ready without client certificate to return 200, got %d", code)
+	}
+	if code := get(anonymous, "/traefik-config"); code != http.StatusForbidden {
+		t.Errorf("expected /traefik-config without client certificate to return 403, got %d", code)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{keyPair},
+	}}}
+	if code := get(client, "/traefik-config"); code != http.StatusOK {
+		t.Errorf("expected /traefik-config with client certificate to return 200, got %d", code)
+	}
+
+	// A certificate from another CA fails the handshake
+	other := generateCert(t, dir, "other", nil)
+	otherPair, err := tls.LoadX509KeyPair(other.certFile, other.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs: roots,
+		// Send the certificate even though the server does not list its CA
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &otherPair, nil
+		},
+	}}}
+	if resp, err := untrusted.Get(server.URL + "/ready"); err == nil {
+		resp.Body.Close()
+		t.Error("expected request with untrusted client certificate to fail")
+	}
+}
+
+func TestRequireClientCert_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	aggregator.RequireClientCert(&aggregator.ServerTLSConfig{}, okHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/traefik-config", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 without client_ca_file, got %d", rec.Code)
+	}
+}
+
+func TestBuildServerTLSConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	serverCert := generateCert(t, dir, "server", nil)
+
+	if cfg, err := aggregator.BuildServerTLSConfig(nil); cfg != nil || err != nil {
+		t.Errorf("expected nil config without server_tls, got %v, %v", cfg, err)
+	}
+
+	if _, err := aggregator.BuildServerTLSConfig(&aggregator.ServerTLSConfig{
+		CertFile: serverCert.certFile,
+		KeyFile:  filepath.Join(dir, "missing.key"),
+	}); err == nil {
+		t.Error("expected error for missing key file")
+	}
+
+	// A key file is not a CA bundle
+	if _, err := aggregator.BuildServerTLSConfig(&aggregator.ServerTLSConfig{
+		CertFile:     serverCert.certFile,
+		KeyFile:      serverCert.keyFile,
+		ClientCAFile: serverCert.keyFile,
+	}); err == nil {
+		t.Error("expected error for client CA without certificates")
+	}
+}
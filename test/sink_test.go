@@ -0,0 +1,178 @@
+package aggregator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestNewFileSink_InvalidFormat(t *testing.T) {
+	if _, err := aggregator.NewFileSink(filepath.Join(t.TempDir(), "out.yml"), "xml"); err == nil {
+		t.Error("expected error for unsupported format, got nil")
+	}
+}
+
+func TestNewFileSink_EmptyPath(t *testing.T) {
+	if _, err := aggregator.NewFileSink("", ""); err == nil {
+		t.Error("expected error for empty path, got nil")
+	}
+}
+
+func TestFileSink_WritesYAMLByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aggregated.yml")
+	sink, err := aggregator.NewFileSink(path, "")
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+
+	written, err := sink.Write(sampleProxyConfig())
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !written {
+		t.Error("expected first write to write the file")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("output file is not valid YAML: %v", err)
+	}
+	if _, ok := parsed["http"]; !ok {
+		t.Errorf("expected http section in output, got:\n%s", data)
+	}
+}
+
+func TestFileSink_JSONFromExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aggregated.json")
+	sink, err := aggregator.NewFileSink(path, "")
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+
+	if _, err := sink.Write(sampleProxyConfig()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var parsed aggregator.HTTPProxyConfig
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("output file is not valid JSON: %v", err)
+	}
+	if len(parsed.HTTP.Routers) != 1 {
+		t.Errorf("expected 1 router in output file, got %d", len(parsed.HTTP.Routers))
+	}
+}
+
+func TestFileSink_SkipsUnchangedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aggregated.yml")
+	sink, _ := aggregator.NewFileSink(path, "yaml")
+
+	if _, err := sink.Write(sampleProxyConfig()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	info, _ := os.Stat(path)
+
+	written, err := sink.Write(sampleProxyConfig())
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if written {
+		t.Error("expected unchanged config not to be rewritten")
+	}
+
+	after, _ := os.Stat(path)
+	if !after.ModTime().Equal(info.ModTime()) {
+		t.Error("expected file modification time to be unchanged")
+	}
+
+	changed := sampleProxyConfig()
+	changed.HTTP.Routers["another"] = aggregator.HTTPRouter{Rule: "Host(`b.example.com`)", Service: "b"}
+	written, err = sink.Write(changed)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !written {
+		t.Error("expected changed config to be written")
+	}
+}
+
+func TestFileSink_SkipsIdenticalFileFromPreviousRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aggregated.yml")
+	first, _ := aggregator.NewFileSink(path, "")
+	first.Write(sampleProxyConfig())
+
+	second, _ := aggregator.NewFileSink(path, "")
+	written, err := second.Write(sampleProxyConfig())
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if written {
+		t.Error("expected identical file left by a previous run not to be rewritten")
+	}
+}
+
+func TestFileSink_LeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	sink, _ := aggregator.NewFileSink(filepath.Join(dir, "aggregated.toml"), "")
+	sink.Write(sampleProxyConfig())
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("unexpected temp file left behind: %s", entry.Name())
+		}
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected exactly 1 file, got %d", len(entries))
+	}
+}
+
+func TestAggregateConfigs_WritesOutputFile(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "aggregated.yml")
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+		OutputFile: &aggregator.OutputFileConfig{Path: path},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected output file to be written: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	agg.AggregateConfigs(context.Background())
+
+	after, _ := os.Stat(path)
+	if !after.ModTime().Equal(info.ModTime()) {
+		t.Error("expected unchanged aggregation not to rewrite the output file")
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "test-downstream-app") {
+		t.Errorf("expected router in output file, got:\n%s", data)
+	}
+}
@@ -0,0 +1,181 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestAggregateConfigs_SNIPassthroughMode(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "secure-router@kubernetes",
+			EntryPoints: []string{"websecure"},
+			Service:     "secure-service",
+			Rule:        "Host(`secure.example.com`) && PathPrefix(`/`)",
+			TLS:         map[string]interface{}{"options": "default"},
+		},
+		{
+			Name:        "plain-router@kubernetes",
+			EntryPoints: []string{"web"},
+			Service:     "plain-service",
+			Rule:        "Host(`plain.example.com`)",
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "test-downstream",
+				APIURL: server.URL,
+				TLS:    &aggregator.TLSConfig{Passthrough: true},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	// Non-TLS router still goes through the HTTP path
+	if _, exists := cachedConfig.HTTP.Routers["test-downstream-plain-router"]; !exists {
+		t.Error("expected non-TLS router to remain an HTTP router")
+	}
+	if _, exists := cachedConfig.HTTP.Routers["test-downstream-secure-router"]; exists {
+		t.Error("expected TLS router not to be emitted as HTTP router")
+	}
+
+	router, exists := cachedConfig.TCP.Routers["test-downstream-secure-router"]
+	if !exists {
+		t.Fatal("expected TCP router 'test-downstream-secure-router' to exist")
+	}
+	if router.Rule != "HostSNI(`secure.example.com`)" {
+		t.Errorf("expected rule 'HostSNI(`secure.example.com`)', got '%s'", router.Rule)
+	}
+	if router.TLS["passthrough"] != true {
+		t.Errorf("expected tls.passthrough true, got %v", router.TLS)
+	}
+	if len(router.EntryPoints) != 1 || router.EntryPoints[0] != "websecure" {
+		t.Errorf("expected entrypoints ['websecure'], got %v", router.EntryPoints)
+	}
+
+	service := cachedConfig.TCP.Services["service-test-downstream-secure-router"]
+	if len(service.LoadBalancer.Servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(service.LoadBalancer.Servers))
+	}
+	if service.LoadBalancer.Servers[0].Address != "127.0.0.1:443" {
+		t.Errorf("expected address '127.0.0.1:443', got '%s'", service.LoadBalancer.Servers[0].Address)
+	}
+}
+
+func TestAggregateConfigs_SNIPassthroughWildcardAndDedup(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{
+			Name:        "pages@kubernetes",
+			EntryPoints: []string{"websecure"},
+			Service:     "pages",
+			Rule:        "HostRegexp(`^[a-zA-Z0-9-]+\\.pages\\.example\\.com$`)",
+			TLS:         map[string]interface{}{"certResolver": "letsencrypt"},
+		},
+		{
+			Name:        "app-root@kubernetes",
+			EntryPoints: []string{"websecure"},
+			Service:     "app",
+			Rule:        "Host(`app.example.com`) && PathPrefix(`/`)",
+			TLS:         map[string]interface{}{"options": "default"},
+		},
+		{
+			Name:        "app-api@kubernetes",
+			EntryPoints: []string{"websecure"},
+			Service:     "app-api",
+			Rule:        "Host(`app.example.com`) && PathPrefix(`/api`)",
+			TLS:         map[string]interface{}{"options": "default"},
+		},
+		{
+			Name:        "path-only@kubernetes",
+			EntryPoints: []string{"websecure"},
+			Service:     "path-only",
+			Rule:        "PathPrefix(`/health`)",
+			TLS:         map[string]interface{}{"options": "default"},
+		},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:            "test-downstream",
+				APIURL:          server.URL,
+				BackendOverride: "traefik.internal:8443",
+				TLS:             &aggregator.TLSConfig{Passthrough: true},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	// pages + one of the two app routers
+	if len(cachedConfig.TCP.Routers) != 2 {
+		t.Errorf("expected 2 TCP routers, got %d", len(cachedConfig.TCP.Routers))
+	}
+
+	pages := cachedConfig.TCP.Routers["test-downstream-pages"]
+	if pages.Rule != "HostSNIRegexp(`^[^.]+\\.pages\\.example\\.com$`)" {
+		t.Errorf("unexpected wildcard rule: %s", pages.Rule)
+	}
+
+	// Rule without hostname falls back to HTTP
+	if _, exists := cachedConfig.HTTP.Routers["test-downstream-path-only"]; !exists {
+		t.Error("expected router without hostname to fall back to HTTP")
+	}
+
+	service := cachedConfig.TCP.Services["service-test-downstream-pages"]
+	if service.LoadBalancer.Servers[0].Address != "traefik.internal:443" {
+		t.Errorf("expected backend override host on port 443, got '%s'", service.LoadBalancer.Servers[0].Address)
+	}
+}
+
+func TestGetSNIPassthroughAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		ds       aggregator.DownstreamConfig
+		expected string
+	}{
+		{"derived from api_url", aggregator.DownstreamConfig{APIURL: "http://traefik:8080"}, "traefik:443"},
+		{"backend override URL", aggregator.DownstreamConfig{APIURL: "http://traefik:8080", BackendOverride: "http://host:808"}, "host:443"},
+		{"backend override host", aggregator.DownstreamConfig{APIURL: "http://traefik:8080", BackendOverride: "host"}, "host:443"},
+		{"tcp override used as-is", aggregator.DownstreamConfig{APIURL: "http://traefik:8080", BackendOverride: "host", TCP: &aggregator.TCPConfig{BackendOverride: "sni.internal:8443"}}, "sni.internal:8443"},
+	}
+
+	for _, tt := range tests {
+		if got := aggregator.GetSNIPassthroughAddress(tt.ds); got != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestBuildHostSNIRule(t *testing.T) {
+	tests := []struct {
+		domains  []string
+		expected string
+	}{
+		{[]string{"example.com"}, "HostSNI(`example.com`)"},
+		{[]string{"a.example.com", "b.example.com"}, "HostSNI(`a.example.com`) || HostSNI(`b.example.com`)"},
+		{[]string{"*.example.com"}, "HostSNIRegexp(`^[^.]+\\.example\\.com$`)"},
+	}
+
+	for _, tt := range tests {
+		if got := aggregator.BuildHostSNIRule(tt.domains); got != tt.expected {
+			t.Errorf("BuildHostSNIRule(%v) = '%s', expected '%s'", tt.domains, got, tt.expected)
+		}
+	}
+}
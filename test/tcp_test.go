@@ -0,0 +1,405 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestFetchDownstreamTCPRouters_Success(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{
+			Name:        "postgres@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "postgres",
+			Rule:        "HostSNI(`db.example.com`)",
+			TLS:         map[string]interface{}{"passthrough": true},
+		},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{Name: "test-downstream", APIURL: server.URL}
+
+	routers, err := aggregator.FetchDownstreamTCPRouters(context.Background(), ds, &http.Client{})
+	if err != nil {
+		t.Fatalf("FetchDownstreamTCPRouters failed: %v", err)
+	}
+	if len(routers) != 1 {
+		t.Fatalf("expected 1 TCP router, got %d", len(routers))
+	}
+	if routers[0].Rule != "HostSNI(`db.example.com`)" {
+		t.Errorf("unexpected rule: %s", routers[0].Rule)
+	}
+}
+
+func TestAggregateConfigs_TCPDisabledByDefault(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{Name: "mqtt@kubernetescrd", EntryPoints: []string{"mqtt"}, Service: "mqtt", Rule: "HostSNI(`*`)"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if got := len(agg.GetCachedConfig().TCP.Routers); got != 0 {
+		t.Errorf("expected no TCP routers when tcp is not enabled, got %d", got)
+	}
+}
+
+func TestAggregateConfigs_TCPPassthrough(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{
+			Name:        "postgres@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "postgres",
+			Rule:        "HostSNI(`db.example.com`)",
+			TLS:         map[string]interface{}{"passthrough": true},
+		},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:            "test-downstream",
+				APIURL:          server.URL,
+				BackendOverride: "traefik.internal",
+				TLS:             &aggregator.TLSConfig{CertResolver: "letsencrypt"},
+				TCP:             &aggregator.TCPConfig{Enabled: true},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	router, exists := cachedConfig.TCP.Routers["test-downstream-postgres"]
+	if !exists {
+		t.Fatal("expected TCP router 'test-downstream-postgres' to exist")
+	}
+	if router.Rule != "HostSNI(`db.example.com`)" {
+		t.Errorf("unexpected rule: %s", router.Rule)
+	}
+	if router.Service != "service-test-downstream-postgres" {
+		t.Errorf("expected service 'service-test-downstream-postgres', got '%s'", router.Service)
+	}
+	if router.TLS["passthrough"] != true {
+		t.Errorf("expected tls.passthrough to be preserved, got %v", router.TLS)
+	}
+	if _, exists := router.TLS["certResolver"]; exists {
+		t.Error("expected no certResolver on passthrough TCP router")
+	}
+
+	service := cachedConfig.TCP.Services["service-test-downstream-postgres"]
+	if len(service.LoadBalancer.Servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(service.LoadBalancer.Servers))
+	}
+	server0 := service.LoadBalancer.Servers[0]
+	if server0.Address != "traefik.internal:443" {
+		t.Errorf("expected address 'traefik.internal:443', got '%s'", server0.Address)
+	}
+	if server0.TLS {
+		t.Error("expected server TLS to be disabled for passthrough")
+	}
+}
+
+func TestAggregateConfigs_TCPTerminatedTLS(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{
+			Name:        "mqtt@kubernetescrd",
+			EntryPoints: []string{"websecure"},
+			Service:     "mqtt",
+			Rule:        "HostSNI(`mqtt.example.com`)",
+			TLS:         map[string]interface{}{"options": "default"},
+		},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "test-downstream",
+				APIURL: server.URL,
+				TLS:    &aggregator.TLSConfig{CertResolver: "letsencrypt"},
+				TCP:    &aggregator.TCPConfig{Enabled: true, BackendOverride: "traefik.internal:8443"},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	router := cachedConfig.TCP.Routers["test-downstream-mqtt"]
+	if router.TLS["certResolver"] != "letsencrypt" {
+		t.Errorf("expected certResolver 'letsencrypt', got '%v'", router.TLS["certResolver"])
+	}
+	domains, ok := router.TLS["domains"].([]aggregator.TLSDomain)
+	if !ok || len(domains) != 1 || domains[0].Main != "mqtt.example.com" {
+		t.Errorf("expected domain 'mqtt.example.com', got %v", router.TLS["domains"])
+	}
+
+	service := cachedConfig.TCP.Services["service-test-downstream-mqtt"]
+	if service.LoadBalancer.Servers[0].Address != "traefik.internal:8443" {
+		t.Errorf("expected address 'traefik.internal:8443', got '%s'", service.LoadBalancer.Servers[0].Address)
+	}
+	if !service.LoadBalancer.Servers[0].TLS {
+		t.Error("expected server TLS to be enabled when upstream terminates TLS")
+	}
+}
+
+func TestAggregateConfigs_TCPEntryPointFilters(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{Name: "internal@file", EntryPoints: []string{"traefik"}, Service: "internal", Rule: "HostSNI(`*`)"},
+		{Name: "db@kubernetescrd", EntryPoints: []string{"postgres"}, Service: "db", Rule: "HostSNI(`*`)"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:              "test-downstream",
+				APIURL:            server.URL,
+				EntryPoints:       []string{"edge-postgres"},
+				IgnoreEntryPoints: []string{"traefik"},
+				TCP:               &aggregator.TCPConfig{Enabled: true, Backends: map[string]string{"postgres": ":5432"}},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	if len(cachedConfig.TCP.Routers) != 1 {
+		t.Fatalf("expected 1 TCP router (ignored internal), got %d", len(cachedConfig.TCP.Routers))
+	}
+	router := cachedConfig.TCP.Routers["test-downstream-db"]
+	if len(router.EntryPoints) != 1 || router.EntryPoints[0] != "edge-postgres" {
+		t.Errorf("expected entrypoints ['edge-postgres'], got %v", router.EntryPoints)
+	}
+	if router.TLS != nil {
+		t.Errorf("expected no TLS config for plain TCP router, got %v", router.TLS)
+	}
+}
+
+func TestAggregateConfigs_TCPFetchErrorFailsDownstream(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Service: "app", Rule: "Host(`app.example.com`)"},
+	}
+	// Only serves HTTP routers, so the TCP endpoint returns 404
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL, TCP: &aggregator.TCPConfig{Enabled: true}},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if got := len(agg.GetCachedConfig().HTTP.Routers); got != 0 {
+		t.Errorf("expected partial downstream fetch to be discarded, got %d routers", got)
+	}
+}
+
+func TestExtractSNIDomainsFromRule(t *testing.T) {
+	tests := []struct {
+		rule        string
+		wildcardFix bool
+		expected    []string
+	}{
+		{"HostSNI(`db.example.com`)", false, []string{"db.example.com"}},
+		{"HostSNI(`a.example.com`) || HostSNI(`b.example.com`)", false, []string{"a.example.com", "b.example.com"}},
+		{"HostSNI(`*`)", false, nil},
+		{"HostSNIRegexp(`^[a-zA-Z0-9-]+\\.example\\.com$`)", false, nil},
+		{"HostSNIRegexp(`^[a-zA-Z0-9-]+\\.example\\.com$`)", true, []string{"*.example.com"}},
+	}
+
+	for _, tt := range tests {
+		got := aggregator.ExtractSNIDomainsFromRule(tt.rule, tt.wildcardFix)
+		if len(got) != len(tt.expected) {
+			t.Errorf("ExtractSNIDomainsFromRule(%q) = %v, expected %v", tt.rule, got, tt.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("ExtractSNIDomainsFromRule(%q) = %v, expected %v", tt.rule, got, tt.expected)
+			}
+		}
+	}
+}
+
+func TestGetTCPBackendAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		ds       aggregator.DownstreamConfig
+		useTLS   bool
+		expected string
+	}{
+		{"derived from api_url with TLS", aggregator.DownstreamConfig{APIURL: "http://traefik:8081"}, true, "traefik:8081"},
+		{"derived from host without port", aggregator.DownstreamConfig{APIURL: "http://traefik"}, true, "traefik:443"},
+		{"backend override without scheme", aggregator.DownstreamConfig{APIURL: "http://traefik", BackendOverride: "backend:8443"}, true, "backend:8443"},
+		{"backend override URL", aggregator.DownstreamConfig{APIURL: "http://traefik", BackendOverride: "https://backend:8443"}, true, "backend:8443"},
+		{"host-only backend override", aggregator.DownstreamConfig{APIURL: "http://traefik", BackendOverride: "traefik.internal"}, true, "traefik.internal:443"},
+		{"backend override URL without port", aggregator.DownstreamConfig{APIURL: "http://traefik", BackendOverride: "https://prod-internal.example.com/"}, false, "prod-internal.example.com:80"},
+		{"backend override URL with path", aggregator.DownstreamConfig{APIURL: "http://traefik", BackendOverride: "http://backend:8000/traefik"}, false, "backend:8000"},
+		{"tcp override wins", aggregator.DownstreamConfig{APIURL: "http://traefik", TCP: &aggregator.TCPConfig{BackendOverride: "db:5432"}}, false, "db:5432"},
+	}
+
+	for _, tt := range tests {
+		if got := aggregator.GetTCPBackendAddress(tt.ds, tt.useTLS); got != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestGetTCPRouterBackendAddress(t *testing.T) {
+	ds := aggregator.DownstreamConfig{
+		APIURL: "http://traefik:8080",
+		TCP: &aggregator.TCPConfig{
+			Enabled:  true,
+			Backends: map[string]string{"postgres": ":5432", "mqtt": "broker.internal:1883"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		entryPoints []string
+		useTLS      bool
+		expected    string
+		ok          bool
+	}{
+		{"port on downstream host", []string{"postgres"}, false, "traefik:5432", true},
+		{"explicit address", []string{"mqtt"}, true, "broker.internal:1883", true},
+		{"first mapped entrypoint wins", []string{"websecure", "mqtt", "postgres"}, true, "broker.internal:1883", true},
+		{"unmapped TLS router falls back", []string{"websecure"}, true, "traefik:8080", true},
+		{"unmapped plain router has no backend", []string{"redis"}, false, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := aggregator.GetTCPRouterBackendAddress(ds, tt.entryPoints, tt.useTLS)
+		if ok != tt.ok || (ok && got != tt.expected) {
+			t.Errorf("%s: expected ('%s', %v), got ('%s', %v)", tt.name, tt.expected, tt.ok, got, ok)
+		}
+	}
+
+	ds.TCP.BackendOverride = "db.internal:6379"
+	if got, ok := aggregator.GetTCPRouterBackendAddress(ds, []string{"redis"}, false); !ok || got != "db.internal:6379" {
+		t.Errorf("expected tcp.backend_override for unmapped plain router, got ('%s', %v)", got, ok)
+	}
+}
+
+func TestAggregateConfigs_TCPBackendsPerEntryPoint(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{Name: "postgres@kubernetescrd", EntryPoints: []string{"postgres"}, Service: "postgres", Rule: "HostSNI(`*`)"},
+		{Name: "mqtt@kubernetescrd", EntryPoints: []string{"mqtt"}, Service: "mqtt", Rule: "HostSNI(`*`)"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:            "cluster",
+				APIURL:          server.URL,
+				BackendOverride: "traefik.internal",
+				TCP: &aggregator.TCPConfig{
+					Enabled:  true,
+					Backends: map[string]string{"postgres": ":5432", "mqtt": ":1883"},
+				},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	config := agg.GetCachedConfig()
+
+	expected := map[string]string{
+		"service-cluster-postgres": "traefik.internal:5432",
+		"service-cluster-mqtt":     "traefik.internal:1883",
+	}
+	for name, address := range expected {
+		servers := config.TCP.Services[name].LoadBalancer.Servers
+		if len(servers) != 1 || servers[0].Address != address {
+			t.Errorf("%s: expected server address '%s', got %v", name, address, servers)
+		}
+	}
+}
+
+func TestAggregateConfigs_TCPRouterWithoutBackend(t *testing.T) {
+	tcpRouters := []aggregator.TraefikRouter{
+		{Name: "postgres@kubernetescrd", EntryPoints: []string{"postgres"}, Service: "postgres", Rule: "HostSNI(`*`)"},
+		{Name: "redis@kubernetescrd", EntryPoints: []string{"redis"}, Service: "redis", Rule: "HostSNI(`*`)"},
+		{Name: "db@kubernetescrd", EntryPoints: []string{"websecure"}, Service: "db", Rule: "HostSNI(`db.example.com`)", TLS: map[string]interface{}{"passthrough": true}},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/tcp/routers":  tcpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "test-downstream",
+				APIURL: server.URL,
+				TCP:    &aggregator.TCPConfig{Enabled: true, Backends: map[string]string{"postgres": ":5432"}},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	config := agg.GetCachedConfig()
+
+	if _, ok := config.TCP.Routers["test-downstream-redis"]; ok {
+		t.Error("expected plain TCP router on an unmapped entrypoint to be skipped")
+	}
+	for _, name := range []string{"test-downstream-postgres", "test-downstream-db"} {
+		if _, ok := config.TCP.Routers[name]; !ok {
+			t.Errorf("expected TCP router %s to be promoted", name)
+		}
+	}
+	if got := agg.Status().Downstreams[0].SkippedByReason["no_backend"]; got != 1 {
+		t.Errorf("expected 1 router skipped as no_backend, got %d", got)
+	}
+}
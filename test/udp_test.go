@@ -0,0 +1,192 @@
+package aggregator_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestFetchDownstreamUDPRouters_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/udp/routers" {
+			t.Errorf("expected path '/api/udp/routers', got '%s'", r.URL.Path)
+		}
+		w.Write([]byte(`[{"name":"dns@docker","entryPoints":["dns"],"service":"coredns","status":"enabled"}]`))
+	}))
+	defer server.Close()
+
+	ds := aggregator.DownstreamConfig{Name: "test-downstream", APIURL: server.URL}
+
+	routers, err := aggregator.FetchDownstreamUDPRouters(context.Background(), ds, &http.Client{})
+	if err != nil {
+		t.Fatalf("FetchDownstreamUDPRouters failed: %v", err)
+	}
+	if len(routers) != 1 || routers[0].Service != "coredns" {
+		t.Errorf("expected 1 UDP router with service 'coredns', got %v", routers)
+	}
+}
+
+func TestAggregateConfigs_UDPRouters(t *testing.T) {
+	udpRouters := []aggregator.TraefikRouter{
+		{Name: "dns@docker", EntryPoints: []string{"dns"}, Service: "coredns"},
+		{Name: "wireguard@docker", EntryPoints: []string{"wg"}, Service: "wireguard"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/udp/routers":  udpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:            "test-downstream",
+				APIURL:          server.URL,
+				BackendOverride: "http://traefik.internal:8000",
+				UDP: &aggregator.UDPConfig{
+					Enabled:     true,
+					Backends:    map[string]string{"dns": ":53", "wg": ":51820"},
+					EntryPoints: map[string]string{"dns": "edge-dns"},
+				},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	if len(cachedConfig.UDP.Routers) != 2 {
+		t.Fatalf("expected 2 UDP routers, got %d", len(cachedConfig.UDP.Routers))
+	}
+
+	router := cachedConfig.UDP.Routers["test-downstream-dns"]
+	if router.Service != "service-test-downstream-dns" {
+		t.Errorf("expected service 'service-test-downstream-dns', got '%s'", router.Service)
+	}
+	if len(router.EntryPoints) != 1 || router.EntryPoints[0] != "edge-dns" {
+		t.Errorf("expected mapped entrypoints ['edge-dns'], got %v", router.EntryPoints)
+	}
+
+	// Unmapped entrypoints keep their downstream name
+	wg := cachedConfig.UDP.Routers["test-downstream-wireguard"]
+	if len(wg.EntryPoints) != 1 || wg.EntryPoints[0] != "wg" {
+		t.Errorf("expected entrypoints ['wg'], got %v", wg.EntryPoints)
+	}
+
+	// Each entrypoint is reached on its own port of the downstream host
+	expected := map[string]string{
+		"service-test-downstream-dns":       "traefik.internal:53",
+		"service-test-downstream-wireguard": "traefik.internal:51820",
+	}
+	for name, address := range expected {
+		servers := cachedConfig.UDP.Services[name].LoadBalancer.Servers
+		if len(servers) != 1 || servers[0].Address != address {
+			t.Errorf("%s: expected server address '%s', got %v", name, address, servers)
+		}
+	}
+}
+
+func TestAggregateConfigs_UDPRouterWithoutBackend(t *testing.T) {
+	udpRouters := []aggregator.TraefikRouter{
+		{Name: "dns@docker", EntryPoints: []string{"dns"}, Service: "coredns"},
+		{Name: "syslog@docker", EntryPoints: []string{"syslog"}, Service: "syslog"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/udp/routers":  udpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:   "test-downstream",
+				APIURL: server.URL,
+				UDP:    &aggregator.UDPConfig{Enabled: true, Backends: map[string]string{"dns": ":53"}},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	if _, ok := agg.GetCachedConfig().UDP.Routers["test-downstream-syslog"]; ok {
+		t.Error("expected UDP router on an entrypoint without backend to be skipped")
+	}
+	if got := agg.Status().Downstreams[0].SkippedByReason["no_backend"]; got != 1 {
+		t.Errorf("expected 1 router skipped as no_backend, got %d", got)
+	}
+}
+
+func TestAggregateConfigs_UDPIgnoreEntryPoints(t *testing.T) {
+	udpRouters := []aggregator.TraefikRouter{
+		{Name: "dns@docker", EntryPoints: []string{"dns"}, Service: "coredns"},
+		{Name: "internal@docker", EntryPoints: []string{"internal-udp"}, Service: "internal"},
+	}
+	server := createTraefikAPIServer(t, map[string]interface{}{
+		"/api/http/routers": []aggregator.TraefikRouter{},
+		"/api/udp/routers":  udpRouters,
+	})
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{
+				Name:              "test-downstream",
+				APIURL:            server.URL,
+				IgnoreEntryPoints: []string{"internal-udp"},
+				UDP:               &aggregator.UDPConfig{Enabled: true, BackendOverride: "traefik.internal:5353"},
+			},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	cachedConfig := agg.GetCachedConfig()
+
+	if len(cachedConfig.UDP.Routers) != 1 {
+		t.Fatalf("expected 1 UDP router (ignored internal), got %d", len(cachedConfig.UDP.Routers))
+	}
+	service := cachedConfig.UDP.Services["service-test-downstream-dns"]
+	if service.LoadBalancer.Servers[0].Address != "traefik.internal:5353" {
+		t.Errorf("expected UDP override address 'traefik.internal:5353', got '%s'", service.LoadBalancer.Servers[0].Address)
+	}
+}
+
+func TestGetUDPBackendAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		udp      *aggregator.UDPConfig
+		expected string
+		ok       bool
+	}{
+		{"port on host from api_url", &aggregator.UDPConfig{Backends: map[string]string{"dns": ":53"}}, "traefik:53", true},
+		{"port on host from udp override", &aggregator.UDPConfig{BackendOverride: "udp.internal:5353", Backends: map[string]string{"dns": ":53"}}, "udp.internal:53", true},
+		{"explicit address", &aggregator.UDPConfig{Backends: map[string]string{"dns": "dns.internal:53"}}, "dns.internal:53", true},
+		{"udp override for unmapped entrypoint", &aggregator.UDPConfig{BackendOverride: "udp.internal:5353"}, "udp.internal:5353", true},
+		{"no backend", &aggregator.UDPConfig{Backends: map[string]string{"wg": ":51820"}}, "", false},
+	}
+
+	for _, tt := range tests {
+		ds := aggregator.DownstreamConfig{APIURL: "http://traefik:8081/api", UDP: tt.udp}
+		got, ok := aggregator.GetUDPBackendAddress(ds, []string{"dns"})
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("%s: expected ('%s', %v), got ('%s', %v)", tt.name, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestMapUDPEntryPoints_NoMapping(t *testing.T) {
+	entryPoints := []string{"dns"}
+
+	got := aggregator.MapUDPEntryPoints(entryPoints, nil)
+	if len(got) != 1 || got[0] != "dns" {
+		t.Errorf("expected ['dns'], got %v", got)
+	}
+}
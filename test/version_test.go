@@ -0,0 +1,147 @@
+package aggregator_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"traefik-config-middleware/pkg/aggregator"
+)
+
+func TestGetConfigVersion_StableForUnchangedConfig(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	first := agg.GetConfigVersion()
+
+	agg.AggregateConfigs(context.Background())
+	second := agg.GetConfigVersion()
+
+	if first.Hash == "" {
+		t.Fatal("expected non-empty hash")
+	}
+	if first != second {
+		t.Errorf("expected version to be unchanged, got %+v and %+v", first, second)
+	}
+}
+
+func TestGetConfigVersion_ChangesWithConfig(t *testing.T) {
+	var changed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := "Host(`a.example.com`)"
+		if changed.Load() {
+			host = "Host(`b.example.com`)"
+		}
+		json.NewEncoder(w).Encode([]aggregator.TraefikRouter{
+			{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: host},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+	first := agg.GetConfigVersion()
+
+	changed.Store(true)
+	time.Sleep(1100 * time.Millisecond) // Modified has second resolution
+	agg.AggregateConfigs(context.Background())
+	second := agg.GetConfigVersion()
+
+	if first.Hash == second.Hash {
+		t.Error("expected hash to change with config content")
+	}
+	if !second.Modified.After(first.Modified) {
+		t.Errorf("expected modification time to advance, got %v then %v", first.Modified, second.Modified)
+	}
+}
+
+func TestGetEncodedConfig_MatchesEncodeConfig(t *testing.T) {
+	routers := []aggregator.TraefikRouter{
+		{Name: "app@kubernetes", EntryPoints: []string{"websecure"}, Rule: "Host(`app.example.com`)"},
+	}
+	server := createMockTraefikServer(t, routers)
+	defer server.Close()
+
+	cfg := &aggregator.Config{
+		Downstream: []aggregator.DownstreamConfig{
+			{Name: "test-downstream", APIURL: server.URL},
+		},
+	}
+
+	agg := aggregator.NewAggregator(cfg, &http.Client{})
+	agg.AggregateConfigs(context.Background())
+
+	for _, format := range []aggregator.Format{aggregator.FormatJSON, aggregator.FormatYAML, aggregator.FormatTOML} {
+		data, version, err := agg.GetEncodedConfig(format)
+		if err != nil {
+			t.Fatalf("GetEncodedConfig(%s) failed: %v", format, err)
+		}
+		if version != agg.GetConfigVersion() {
+			t.Errorf("expected version of current config for %s", format)
+		}
+
+		var expected bytes.Buffer
+		aggregator.EncodeConfig(&expected, agg.GetCachedConfig(), format)
+		if !bytes.Equal(data, expected.Bytes()) {
+			t.Errorf("cached %s encoding differs from EncodeConfig output", format)
+		}
+	}
+}
+
+func TestConfigVersion_ETagDiffersPerFormat(t *testing.T) {
+	agg := aggregator.NewAggregator(&aggregator.Config{}, &http.Client{})
+	version := agg.GetConfigVersion()
+
+	jsonTag := version.ETag(aggregator.FormatJSON)
+	yamlTag := version.ETag(aggregator.FormatYAML)
+
+	if jsonTag == yamlTag {
+		t.Error("expected different ETags per format")
+	}
+	if jsonTag[0] != '"' || jsonTag[len(jsonTag)-1] != '"' {
+		t.Errorf("expected quoted ETag, got %s", jsonTag)
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	etag := `"abc-json"`
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{`"abc-json"`, true},
+		{`W/"abc-json"`, true},
+		{`"other", "abc-json"`, true},
+		{`*`, true},
+		{`"abc-yaml"`, false},
+		{`"other"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := aggregator.MatchesETag(tt.header, etag); got != tt.expected {
+			t.Errorf("MatchesETag(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+}